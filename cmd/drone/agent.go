@@ -0,0 +1,159 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/drone/drone/pkg/agent"
+	"github.com/drone/drone/pkg/build"
+	"github.com/drone/drone/pkg/build/docker"
+	"github.com/drone/drone/pkg/build/log"
+	"github.com/drone/drone/pkg/build/script"
+)
+
+// runAgent pulls builds from droned and runs them on the
+// local Docker host, with one poller for each build that
+// can run at the same time.
+func runAgent() {
+	if len(*server) == 0 {
+		log.Err("Error: the droned server url must be provided with --server")
+		os.Exit(1)
+		return
+	}
+	if *concurrency < 1 {
+		log.Err("Error: --concurrency must be at least 1")
+		os.Exit(1)
+		return
+	}
+
+	name := *agentName
+	if len(name) == 0 {
+		name, _ = os.Hostname()
+	}
+
+	dockerClient := docker.New()
+
+	// make sure the runtime exists
+	if _, err := build.NewRuntime(*buildRuntime, dockerClient); err != nil {
+		log.Err(err.Error())
+		os.Exit(1)
+		return
+	}
+
+	// remove containers and images left behind
+	// if the agent exited during a build, once they
+	// are older than the longest build.
+	build.ReapAge = *timeout
+	if *buildRuntime != build.RuntimeShell {
+		go reap(dockerClient)
+	}
+
+	client := agent.NewClient(*server, *agentToken, name)
+	client.Labels = script.SplitLabels(*labels)
+	log.Noticef("agent %s is pulling builds from %s", name, client.Server)
+
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pull(client, dockerClient)
+		}()
+	}
+	wg.Wait()
+}
+
+// reap removes orphaned containers and images from
+// the local Docker host at startup, and then every
+// 10 minutes.
+func reap(dockerClient *docker.Client) {
+	for {
+		if _, err := build.Reap(dockerClient); err != nil {
+			log.Errf("Error removing orphaned containers: %s", err)
+		}
+		time.Sleep(10 * time.Minute)
+	}
+}
+
+// pull runs the builds assigned to the agent, one at a
+// time, until the server rejects the agent token.
+func pull(client *agent.Client, dockerClient *docker.Client) {
+	for {
+		job, err := client.Pull(*concurrency)
+		switch {
+		case err == agent.ErrNotAuthorized:
+			log.Err(err.Error())
+			os.Exit(1)
+		case err != nil:
+			// the server may be restarting, so
+			// wait before polling again.
+			log.Errf("Error pulling build from %s: %s", client.Server, err)
+			time.Sleep(10 * time.Second)
+		case job != nil:
+			runJob(client, dockerClient, job)
+		}
+	}
+}
+
+// runJob runs the build, streaming the output to the
+// server, and reports the result of the build.
+func runJob(client *agent.Client, dockerClient *docker.Client, job *agent.Job) {
+	// the build is only run once it is acknowledged, since
+	// the server offers it again if the lease has expired.
+	if err := client.Ack(job.ID); err != nil {
+		log.Errf("Error acknowledging build %s: %s", job.Repo.Name, err)
+		return
+	}
+	log.Noticef("starting build %s", job.Repo.Name)
+
+	result := &agent.Result{}
+	runtime, err := build.NewRuntime(*buildRuntime, dockerClient)
+	if err != nil {
+		result.Error = err.Error()
+		done(client, job, result)
+		return
+	}
+
+	// the output is streamed to the server while the
+	// build is running. If the stream fails, writes to
+	// the pipe return the error instead of blocking.
+	reader, writer := io.Pipe()
+	streamed := make(chan error, 1)
+	go func() {
+		err := client.Stream(job.ID, reader)
+		reader.CloseWithError(err)
+		streamed <- err
+	}()
+
+	builder := build.New(runtime)
+	builder.Build = job.Script
+	builder.Repo = job.Repo
+	builder.Key = job.Key
+	builder.Limits = job.Limits
+	builder.Stdout = writer
+	builder.Timeout = job.Timeout
+
+	if err := builder.Run(); err != nil {
+		log.Errf("Error executing build: %s", err.Error())
+		result.Error = err.Error()
+	}
+	result.State = builder.BuildState
+
+	writer.Close()
+	if err := <-streamed; err != nil {
+		log.Errf("Error streaming build output to %s: %s", client.Server, err)
+	}
+
+	done(client, job, result)
+}
+
+// done reports the result of the build to the server.
+func done(client *agent.Client, job *agent.Job, result *agent.Result) {
+	if err := client.Done(job.ID, result); err != nil {
+		log.Errf("Error reporting build result to %s: %s", client.Server, err)
+		return
+	}
+	log.Noticef("finished build %s", job.Repo.Name)
+}
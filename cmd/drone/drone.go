@@ -7,11 +7,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/drone/drone/pkg/build"
 	"github.com/drone/drone/pkg/build/docker"
+	"github.com/drone/drone/pkg/build/git"
 	"github.com/drone/drone/pkg/build/log"
 	"github.com/drone/drone/pkg/build/repo"
 	"github.com/drone/drone/pkg/build/script"
@@ -31,11 +33,31 @@ var (
 	// this will default to 500 minutes (6 hours)
 	timeout = flag.Duration("timeout", 300*time.Minute, "")
 
+	// runtime used to run the build, either docker
+	// or shell.
+	buildRuntime = flag.String("runtime", "docker", "")
+
 	// runs Drone with verbose output if True
 	verbose = flag.Bool("v", false, "")
 
 	// displays the help / usage if True
 	help = flag.Bool("h", false, "")
+
+	// simulated build parameters used by drone script
+	// to render the build for a branch, commit or pull
+	// request, optionally cloned from a remote url.
+	branch = flag.String("branch", "master", "")
+	commit = flag.String("commit", "", "")
+	pr     = flag.String("pr", "", "")
+	remote = flag.String("remote", "", "")
+
+	// remote build agent parameters used by drone agent
+	// to pull builds from the droned server.
+	server      = flag.String("server", "", "")
+	agentToken  = flag.String("token", os.Getenv("DRONE_AGENT_TOKEN"), "")
+	agentName   = flag.String("name", "", "")
+	concurrency = flag.Int("concurrency", 1, "")
+	labels      = flag.String("labels", "", "")
 )
 
 func init() {
@@ -51,6 +73,14 @@ func main() {
 	flag.Usage = usage
 	flag.Parse()
 
+	// flags may also follow the command, for
+	// example drone script --pr=42
+	args := flag.Args()
+	if len(args) > 1 {
+		flag.CommandLine.Parse(args[1:])
+		args = append([]string{args[0]}, flag.Args()...)
+	}
+
 	if *help {
 		flag.Usage()
 		os.Exit(0)
@@ -61,7 +91,6 @@ func main() {
 	}
 
 	// Must speicify a command
-	args := flag.Args()
 	if len(args) == 0 {
 		flag.Usage()
 		os.Exit(0)
@@ -100,6 +129,27 @@ func main() {
 		path = filepath.Join(path, ".drone.yml")
 		vet(path)
 
+	// run drone script where the path to the
+	// source directory is provided
+	case args[0] == "script" && len(args) == 2:
+		path := args[1]
+		path = filepath.Clean(path)
+		path, _ = filepath.Abs(path)
+		path = filepath.Join(path, ".drone.yml")
+		printScript(path)
+
+	// run drone script assuming the current
+	// working directory contains the drone.yml
+	case args[0] == "script" && len(args) == 1:
+		path, _ := os.Getwd()
+		path = filepath.Join(path, ".drone.yml")
+		printScript(path)
+
+	// run drone agent, which pulls builds from
+	// the droned server
+	case args[0] == "agent" && len(args) == 1:
+		runAgent()
+
 	// print the help message
 	case args[0] == "help" && len(args) == 1:
 		flag.Usage()
@@ -109,22 +159,35 @@ func main() {
 }
 
 func vet(path string) {
-	// parse the Drone yml file
-	script, err := script.ParseBuildFile(path)
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		log.Err(err.Error())
 		os.Exit(1)
 		return
 	}
 
+	// validate the Drone yml file and print all
+	// errors and warnings
+	problems := script.Validate(data, nil)
+	for _, problem := range problems {
+		if problem.Line == 0 {
+			fmt.Printf("%s: %s: %s\n", path, problem.Level, problem.Message)
+		} else {
+			fmt.Printf("%s:%d: %s: %s\n", path, problem.Line, problem.Level, problem.Message)
+		}
+	}
+	if problems.HasErrors() {
+		os.Exit(1)
+		return
+	}
+
 	// print the Drone yml as parsed
-	out, _ := goyaml.Marshal(script)
-	log.Noticef("parsed yaml:\n%s", string(out))
+	s, _ := script.ParseBuild(data, nil)
+	out, _ := goyaml.Marshal(s)
+	log.Debugf("parsed yaml:\n%s", string(out))
 }
 
-func run(path string) {
-	dockerClient := docker.New()
-
+func printScript(path string) {
 	// parse the Drone yml file
 	s, err := script.ParseBuildFile(path)
 	if err != nil {
@@ -133,35 +196,78 @@ func run(path string) {
 		return
 	}
 
-	// get the repository root directory
-	dir := filepath.Dir(path)
-	code := repo.Repo{
-		Name:   filepath.Base(dir),
-		Branch: "HEAD", // should we do this?
-		Path:   dir,
+	// the build is either copied from the local
+	// directory, or cloned from the remote url the
+	// same way droned clones the repository.
+	var code *repo.Repo
+	if len(*remote) == 0 {
+		code = getLocalRepo(filepath.Dir(path))
+		code.Branch = *branch
+	} else {
+		code = &repo.Repo{
+			Name:   getRemoteName(*remote),
+			Path:   *remote,
+			Branch: *branch,
+			Dir:    filepath.Join("/var/cache/drone/src", getRemoteName(*remote)),
+			Depth:  git.GitDepth(s.Git),
+		}
 	}
+	code.Commit = *commit
+	code.PR = *pr
 
-	// does the local repository match the
-	// $GOPATH/src/{package} pattern? This is
-	// important so we know the target location
-	// where the code should be copied inside
-	// the container.
-	if gopath, ok := getRepoPath(dir); ok {
-		code.Dir = gopath
+	runtime, err := build.NewRuntime(*buildRuntime, nil)
+	if err != nil {
+		log.Err(err.Error())
+		os.Exit(1)
+		return
+	}
 
-	} else if gopath, ok := getGoPath(dir); ok {
-		// in this case we found a GOPATH and
-		// reverse engineered the package path
-		code.Dir = gopath
+	builder := build.New(runtime)
+	builder.Build = s
+	builder.Repo = code
 
-	} else {
-		// otherwise just use directory name
-		code.Dir = filepath.Base(dir)
+	fmt.Printf("==> drone <==\n%s\n", builder.BuildScript())
+
+	// the proxy script and container are
+	// specific to the docker runtime.
+	d, ok := runtime.(*build.Docker)
+	if !ok {
+		return
 	}
+	fmt.Printf("==> proxy.sh <==\n%s\n", d.ProxyScript(builder))
+
+	// print the build container configuration
+	conf := d.Config(builder)
+	fmt.Println("==> container <==")
+	fmt.Printf("Image: %s\n", conf.Image)
+	fmt.Printf("User: %s\n", conf.User)
+	fmt.Printf("WorkingDir: %s\n", conf.WorkingDir)
+	fmt.Printf("Entrypoint: %s\n", strings.Join(conf.Entrypoint, " "))
+	for _, env := range conf.Env {
+		fmt.Printf("Env: %s\n", env)
+	}
+}
 
-	// this is where the code gets uploaded to the container
-	// TODO move this code to the build package
-	code.Dir = filepath.Join("/var/cache/drone/src", filepath.Clean(code.Dir))
+func run(path string) {
+	dockerClient := docker.New()
+
+	// make sure the runtime exists
+	if _, err := build.NewRuntime(*buildRuntime, dockerClient); err != nil {
+		log.Err(err.Error())
+		os.Exit(1)
+		return
+	}
+
+	// parse the Drone yml file
+	s, err := script.ParseBuildFile(path)
+	if err != nil {
+		log.Err(err.Error())
+		os.Exit(1)
+		return
+	}
+
+	// get the repository root directory
+	code := getLocalRepo(filepath.Dir(path))
 
 	// track all build results
 	var builders []*build.Builder
@@ -181,9 +287,10 @@ func run(path string) {
 
 	// loop through and create builders
 	for _, b := range builds { //script.Builds {
-		builder := build.New(dockerClient)
+		runtime, _ := build.NewRuntime(*buildRuntime, dockerClient)
+		builder := build.New(runtime)
 		builder.Build = b
-		builder.Repo = &code
+		builder.Repo = code
 		builder.Key = key
 		builder.Stdout = os.Stdout
 		builder.Timeout = *timeout
@@ -240,6 +347,39 @@ func run(path string) {
 	os.Exit(exit)
 }
 
+// getLocalRepo returns the repository located in the
+// local directory, which is copied into the container.
+func getLocalRepo(dir string) *repo.Repo {
+	code := repo.Repo{
+		Name:   filepath.Base(dir),
+		Branch: "HEAD", // should we do this?
+		Path:   dir,
+	}
+
+	// does the local repository match the
+	// $GOPATH/src/{package} pattern? This is
+	// important so we know the target location
+	// where the code should be copied inside
+	// the container.
+	if gopath, ok := getRepoPath(dir); ok {
+		code.Dir = gopath
+
+	} else if gopath, ok := getGoPath(dir); ok {
+		// in this case we found a GOPATH and
+		// reverse engineered the package path
+		code.Dir = gopath
+
+	} else {
+		// otherwise just use directory name
+		code.Dir = filepath.Base(dir)
+	}
+
+	// this is where the code gets uploaded to the container
+	// TODO move this code to the build package
+	code.Dir = filepath.Join("/var/cache/drone/src", filepath.Clean(code.Dir))
+	return &code
+}
+
 func runSequential(builders []*build.Builder) {
 	// loop through and execute each build
 	for _, builder := range builders {
@@ -277,7 +417,9 @@ Usage:
 
 The commands are:
 
+   agent           pull builds from a droned server and run them
    build           build and test the repository
+   script          print the generated build script, proxy.sh and container
    version         print the version number
    vet             validate the yaml configuration file
 
@@ -285,10 +427,33 @@ The commands are:
   -h               display this help and exit
   --parallel       runs drone build tasks in parallel
   --timeout=300ms  timeout build after 300 milliseconds
+  --runtime=shell  runs the build with the docker (default) or shell runtime
+
+The script command accepts the following flags:
+
+  --branch=master  simulates a build of the branch
+  --commit=sha     simulates a build of the commit
+  --pr=42          simulates a build of the pull request,
+                   which omits the deploy, publish and notify steps
+  --remote=url     clones the repository from the url instead of
+                   copying the local directory, as droned does
+
+The agent command accepts the following flags:
+
+  --server=url     url of the droned server
+  --token=secret   token shared with the droned server, which
+                   defaults to $DRONE_AGENT_TOKEN
+  --name=host      name of the agent, which defaults to the hostname
+  --concurrency=2  number of builds the agent runs at the same time
+  --labels=a,b     labels of the agent, such as privileged or highmem,
+                   which are matched against the labels of each build
 
 Examples:
   drone build                 builds the source in the pwd
   drone build /path/to/repo   builds the source repository
+  drone script --pr=42        prints the build script for pull request 42
+  drone agent --server=http://drone.example.com
+                              runs builds for the droned server
 
 Use "drone help [command]" for more information about a command.
 `)
@@ -65,6 +65,22 @@ func getGitOrigin(dir string) (path string, ok bool) {
 	return
 }
 
+// getRemoteName returns the repository name from the
+// remote url, for example github.com/drone/drone for
+// git@github.com:drone/drone.git
+func getRemoteName(url string) string {
+	name := url
+	if index := strings.Index(name, "://"); index != -1 {
+		name = name[index+3:]
+	}
+	if index := strings.Index(name, "@"); index != -1 {
+		name = name[index+1:]
+	}
+	name = strings.Replace(name, ":", "/", -1)
+	name = strings.TrimSuffix(name, ".git")
+	return filepath.Clean(name)
+}
+
 // prints the time as a human readable string
 func humanizeDuration(d time.Duration) string {
 	if seconds := int(d.Seconds()); seconds < 1 {
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"log"
 	"net/http"
+	"os"
 	"runtime"
 	"strings"
 	"time"
@@ -15,11 +16,14 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/russross/meddler"
 
+	"github.com/drone/drone/pkg/build"
 	"github.com/drone/drone/pkg/build/docker"
+	"github.com/drone/drone/pkg/build/script"
 	"github.com/drone/drone/pkg/channel"
 	"github.com/drone/drone/pkg/database"
 	"github.com/drone/drone/pkg/database/migrate"
 	"github.com/drone/drone/pkg/handler"
+	"github.com/drone/drone/pkg/mail"
 	"github.com/drone/drone/pkg/queue"
 )
 
@@ -47,6 +51,23 @@ var (
 	// this will default to 500 minutes (6 hours)
 	timeout time.Duration
 
+	// runtime used to run builds, either docker
+	// or shell. The shell runtime runs builds on
+	// the host and should only be used for trusted
+	// repositories.
+	buildRuntime string
+
+	// token shared with remote build agents, which
+	// pull builds from droned. Agents are disabled
+	// if the token is empty.
+	agentToken string
+
+	// comma separated labels of the local host,
+	// such as privileged or highmem, which are
+	// matched against the labels required by
+	// each build.
+	labels string
+
 	// commit sha for the current build.
 	version string
 )
@@ -60,11 +81,20 @@ func main() {
 	flag.StringVar(&sslcert, "sslcert", "", "")
 	flag.StringVar(&sslkey, "sslkey", "", "")
 	flag.DurationVar(&timeout, "timeout", 300*time.Minute, "")
+	flag.StringVar(&buildRuntime, "runtime", "docker", "")
+	flag.StringVar(&agentToken, "agent-token", os.Getenv("DRONE_AGENT_TOKEN"), "")
+	flag.StringVar(&labels, "labels", "", "")
 	flag.Parse()
 
 	// validate the TLS arguments
 	checkTLSFlags()
 
+	// identify this version of drone to
+	// the Docker daemon.
+	if len(version) != 0 {
+		docker.DroneVersion = version
+	}
+
 	// setup database and handlers
 	setupDatabase()
 	setupStatic()
@@ -134,10 +164,43 @@ func setupStatic() {
 
 // setup routes for serving dynamic content.
 func setupHandlers() {
-	queueRunner := queue.NewBuildRunner(docker.New(), timeout)
-	queue := queue.Start(runtime.NumCPU(), queueRunner)
+	queueRunner, err := queue.NewBuildRunner(buildRuntime, timeout)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// builds run on the local host until Docker hosts
+	// are registered, which is only supported by the
+	// docker runtime.
+	var dockerClient *docker.Client
+	if buildRuntime != build.RuntimeShell {
+		dockerClient = docker.New()
+	}
+	pool := queue.NewPool(dockerClient, runtime.NumCPU(), script.SplitLabels(labels))
+	if err := pool.Reload(); err != nil {
+		log.Fatal(err)
+	}
+	pool.StartHealthCheck(30 * time.Second)
+
+	// remove containers and images left behind
+	// if droned exited during a build, once they
+	// are older than the longest build.
+	build.ReapAge = timeout
+	pool.StartReaper(10 * time.Minute)
+
+	agents := queue.NewAgents(pool, timeout)
+
+	queue := queue.Start(pool, queueRunner)
+	queue.StartScheduler()
+
+	// deliver queued emails in the background,
+	// and retry failed deliveries.
+	mail.StartQueue(time.Minute)
 
 	hookHandler := handler.NewHookHandler(queue)
+	triggerHandler := handler.NewTriggerHandler(queue)
+	nodeHandler := handler.NewNodeHandler(pool)
+	agentHandler := handler.NewAgentHandler(agents, agentToken)
 
 	m := pat.New()
 	m.Get("/login", handler.ErrorHandler(handler.Login))
@@ -190,16 +253,28 @@ func setupHandlers() {
 	// handlers for system administration
 	m.Get("/account/admin/settings", handler.AdminHandler(handler.AdminSettings))
 	m.Post("/account/admin/settings", handler.AdminHandler(handler.AdminSettingsUpdate))
+	m.Post("/account/admin/settings/email", handler.AdminHandler(handler.AdminSettingsTestEmail))
 	m.Get("/account/admin/users/edit", handler.AdminHandler(handler.AdminUserEdit))
 	m.Post("/account/admin/users/edit", handler.AdminHandler(handler.AdminUserUpdate))
 	m.Post("/account/admin/users/delete", handler.AdminHandler(handler.AdminUserDelete))
 	m.Get("/account/admin/users/add", handler.AdminHandler(handler.AdminUserAdd))
 	m.Post("/account/admin/users", handler.AdminHandler(handler.AdminUserInvite))
 	m.Get("/account/admin/users", handler.AdminHandler(handler.AdminUserList))
+	m.Post("/account/admin/nodes/delete", handler.AdminHandler(nodeHandler.Delete))
+	m.Post("/account/admin/nodes", handler.AdminHandler(nodeHandler.Create))
+	m.Get("/account/admin/nodes", handler.AdminHandler(nodeHandler.List))
+	m.Post("/account/admin/docker/purge", handler.AdminHandler(nodeHandler.Purge))
+	m.Get("/account/admin/docker", handler.AdminHandler(nodeHandler.Usage))
 
 	// handlers for GitHub post-commit hooks
 	m.Post("/hook/github.com", handler.ErrorHandler(hookHandler.Hook))
 
+	// handlers for remote build agents
+	m.Post("/api/agent/pull", handler.ErrorHandler(agentHandler.Pull))
+	m.Post("/api/agent/jobs/:id/ack", handler.ErrorHandler(agentHandler.Ack))
+	m.Post("/api/agent/jobs/:id/output", handler.ErrorHandler(agentHandler.Output))
+	m.Post("/api/agent/jobs/:id/result", handler.ErrorHandler(agentHandler.Result))
+
 	// handlers for first-time installation
 	m.Get("/install", handler.ErrorHandler(handler.Install))
 	m.Post("/install", handler.ErrorHandler(handler.InstallPost))
@@ -207,11 +282,17 @@ func setupHandlers() {
 	// handlers for repository, commits and build details
 	m.Get("/:host/:owner/:name/commit/:commit/build/:label/out.txt", handler.RepoHandler(handler.BuildOut))
 	m.Get("/:host/:owner/:name/commit/:commit/build/:label", handler.RepoHandler(handler.CommitShow))
+	m.Post("/:host/:owner/:name/commit/:commit/build/:label/notifications/:id/retry", handler.RepoAdminHandler(handler.NotificationRetry))
 	m.Get("/:host/:owner/:name/commit/:commit", handler.RepoHandler(handler.CommitShow))
+	m.Post("/:host/:owner/:name/build", handler.RepoAdminHandler(triggerHandler.Build))
 	m.Get("/:host/:owner/:name/tree", handler.RepoHandler(handler.RepoDashboard))
 	m.Get("/:host/:owner/:name/status.png", handler.ErrorHandler(handler.Badge))
 	m.Get("/:host/:owner/:name/settings", handler.RepoAdminHandler(handler.RepoSettingsForm))
 	m.Get("/:host/:owner/:name/params", handler.RepoAdminHandler(handler.RepoParamsForm))
+	m.Get("/:host/:owner/:name/schedules", handler.RepoAdminHandler(handler.RepoSchedules))
+	m.Post("/:host/:owner/:name/schedules/delete", handler.RepoAdminHandler(handler.RepoScheduleDelete))
+	m.Post("/:host/:owner/:name/schedules", handler.RepoAdminHandler(handler.RepoScheduleCreate))
+	m.Get("/:host/:owner/:name/deliveries", handler.RepoAdminHandler(handler.RepoDeliveries))
 	m.Get("/:host/:owner/:name/badges", handler.RepoAdminHandler(handler.RepoBadges))
 	m.Get("/:host/:owner/:name/keys", handler.RepoAdminHandler(handler.RepoKeys))
 	m.Get("/:host/:owner/:name/delete", handler.RepoAdminHandler(handler.RepoDeleteForm))
@@ -0,0 +1,58 @@
+// Package agent implements the protocol used by remote build
+// agents, which pull builds from droned over HTTP, run them
+// on their own Docker host and stream the output back.
+package agent
+
+import (
+	"time"
+
+	"github.com/drone/drone/pkg/build"
+	"github.com/drone/drone/pkg/build/repo"
+	"github.com/drone/drone/pkg/build/script"
+)
+
+// TokenHeader is the HTTP header used to send the
+// shared token that authenticates the agent.
+const TokenHeader = "X-Drone-Agent-Token"
+
+// PollTimeout is the maximum amount of time droned
+// holds a request for a build before responding
+// that no build is available.
+const PollTimeout = 30 * time.Second
+
+// Job is a build that is assigned to an agent.
+type Job struct {
+	ID string `json:"id"`
+
+	// Build instructions from the .drone.yml
+	// file, unmarshalled.
+	Script *script.Build `json:"script"`
+
+	// Repository that is cloned into
+	// the build environment.
+	Repo *repo.Repo `json:"repo"`
+
+	// Key is the identity file, such as an
+	// RSA private key, used to clone the repository.
+	Key []byte `json:"key"`
+
+	// Limits are the memory and CPU constraints
+	// of the build and service containers.
+	Limits build.Limits `json:"limits"`
+
+	// Timeout is the maximum amount of time
+	// the build is allowed to run.
+	Timeout time.Duration `json:"timeout"`
+}
+
+// Result is the outcome of a Job, reported by
+// the agent after the build exits.
+type Result struct {
+	// State of the exited build, or nil if the
+	// build could not be started.
+	State *build.BuildState `json:"state"`
+
+	// Error is the reason the build could
+	// not be run, if any.
+	Error string `json:"error"`
+}
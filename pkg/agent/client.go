@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Returned if droned rejects the agent token.
+	ErrNotAuthorized = errors.New("Agent token was rejected by the server")
+
+	// Returned if the job no longer exists, for
+	// example because it timed out on the server.
+	ErrJobNotFound = errors.New("Job does not exist or is no longer running")
+)
+
+// Client is used by an agent to pull jobs from droned
+// and report the output and result of each build.
+type Client struct {
+	// URL of the droned server, for
+	// example http://drone.example.com
+	Server string
+
+	// Token shared by droned and its agents.
+	Token string
+
+	// Name identifies the agent, and should be
+	// unique, for example the hostname.
+	Name string
+
+	// Labels advertised by the agent, which are
+	// matched against the labels required by
+	// each build.
+	Labels []string
+
+	client *http.Client
+}
+
+// NewClient creates a Client for the droned
+// server at the given URL.
+func NewClient(server, token, name string) *Client {
+	return &Client{
+		Server: strings.TrimRight(server, "/"),
+		Token:  token,
+		Name:   name,
+		client: &http.Client{},
+	}
+}
+
+// Pull waits for droned to assign a job to the agent,
+// and returns nil if no job is available before the
+// poll times out. The concurrency is the number of
+// builds the agent is able to run at the same time.
+func (c *Client) Pull(concurrency int) (*Job, error) {
+	params := url.Values{}
+	params.Set("name", c.Name)
+	params.Set("concurrency", strconv.Itoa(concurrency))
+	params.Set("labels", strings.Join(c.Labels, ","))
+
+	resp, err := c.do("POST", "/api/agent/pull?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+
+	job := Job{}
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Ack acknowledges that the agent received the job, which
+// must be done before the lease expires, otherwise droned
+// offers the build again, and ErrJobNotFound is returned.
+func (c *Client) Ack(id string) error {
+	resp, err := c.do("POST", "/api/agent/jobs/"+id+"/ack", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Stream sends the build output to droned, which stores
+// it and broadcasts it to the browser, until the reader
+// returns io.EOF.
+func (c *Client) Stream(id string, output io.Reader) error {
+	resp, err := c.do("POST", "/api/agent/jobs/"+id+"/output", output)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Done reports the result of the build to droned.
+func (c *Client) Done(id string, result *Result) error {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do("POST", "/api/agent/jobs/"+id+"/result", bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// helper function to make authenticated requests to droned.
+// An error is returned if the response status is not 2xx.
+func (c *Client) do(method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.Server+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(TokenHeader, c.Token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		resp.Body.Close()
+		return nil, ErrNotAuthorized
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrJobNotFound
+	case resp.StatusCode >= 300:
+		msg, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("Unexpected response from %s: %s %s", c.Server, resp.Status, msg)
+	}
+	return resp, nil
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/pkg/build"
+	"github.com/drone/drone/pkg/build/repo"
+)
+
+func TestPull(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get(TokenHeader) != "secret":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path != "/api/agent/pull":
+			w.WriteHeader(http.StatusNotFound)
+		case r.FormValue("name") != "agent1" || r.FormValue("concurrency") != "2":
+			w.WriteHeader(http.StatusBadRequest)
+		case r.Method != "POST":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			json.NewEncoder(w).Encode(&Job{ID: "1", Repo: &repo.Repo{Name: "github.com/drone/drone"}})
+		}
+	}))
+	defer server.Close()
+
+	job, err := NewClient(server.URL+"/", "secret", "agent1").Pull(2)
+	if err != nil {
+		t.Fatalf("Expected job, got error %s", err)
+	}
+	if job.ID != "1" || job.Repo.Name != "github.com/drone/drone" {
+		t.Errorf("Expected job 1 for github.com/drone/drone, got %s %s", job.ID, job.Repo.Name)
+	}
+
+	if _, err := NewClient(server.URL, "invalid", "agent1").Pull(2); err != ErrNotAuthorized {
+		t.Errorf("Expected ErrNotAuthorized, got %v", err)
+	}
+}
+
+func TestPullNoContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	job, err := NewClient(server.URL, "secret", "agent1").Pull(1)
+	if job != nil || err != nil {
+		t.Errorf("Expected no job and no error, got %v %v", job, err)
+	}
+}
+
+func TestStreamAndDone(t *testing.T) {
+	var acked bool
+	var output string
+	var result Result
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/agent/jobs/1/ack":
+			acked = true
+		case "/api/agent/jobs/1/output":
+			body, _ := ioutil.ReadAll(r.Body)
+			output = string(body)
+		case "/api/agent/jobs/1/result":
+			json.NewDecoder(r.Body).Decode(&result)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret", "agent1")
+	if err := client.Ack("1"); err != nil || !acked {
+		t.Fatalf("Expected job to be acknowledged, got %v", err)
+	}
+	if err := client.Ack("2"); err != ErrJobNotFound {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+	if err := client.Stream("1", strings.NewReader("$ go test\nPASS\n")); err != nil {
+		t.Fatal(err)
+	}
+	if output != "$ go test\nPASS\n" {
+		t.Errorf("Expected build output to be streamed, got %q", output)
+	}
+
+	if err := client.Done("1", &Result{State: &build.BuildState{ExitCode: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if result.State == nil || result.State.ExitCode != 1 {
+		t.Errorf("Expected exit code 1 to be reported, got %v", result.State)
+	}
+
+	if err := client.Done("2", &Result{}); err != ErrJobNotFound {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+}
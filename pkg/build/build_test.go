@@ -0,0 +1,129 @@
+package build
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/pkg/build/docker"
+	"github.com/drone/drone/pkg/build/repo"
+	"github.com/drone/drone/pkg/build/script"
+)
+
+func TestConfig(t *testing.T) {
+	var tests = []struct {
+		image string
+		tag   string
+		user  string
+		home  string
+	}{
+		{"go1.2", "bradrydzewski/go:1.2", "ubuntu", "HOME=/home/ubuntu"},
+		{"drone/custom", "drone/custom", "ubuntu", "HOME=/home/ubuntu"},
+		{"mischief/docker-golang", "mischief/docker-golang", "root", "HOME=/root"},
+	}
+
+	for _, test := range tests {
+		d := NewDocker(nil)
+		b := New(d)
+		b.Build = &script.Build{Image: test.image}
+		b.Repo = &repo.Repo{Dir: "/var/cache/drone/src/github.com/foo/bar"}
+
+		conf := d.Config(b)
+		if conf.Image != test.tag {
+			t.Errorf("Expected image %s, got %s", test.tag, conf.Image)
+		}
+		if conf.User != test.user {
+			t.Errorf("Expected user %s for image %s, got %s", test.user, test.image, conf.User)
+		}
+		if conf.Env[0] != test.home {
+			t.Errorf("Expected %s for image %s, got %s", test.home, test.image, conf.Env[0])
+		}
+		if conf.WorkingDir != b.Repo.Dir {
+			t.Errorf("Expected working dir %s, got %s", b.Repo.Dir, conf.WorkingDir)
+		}
+		if entrypoint := strings.Join(conf.Entrypoint, " "); entrypoint != "/bin/bash -e /drone/drone" {
+			t.Errorf("Expected the build script entrypoint, got %s", entrypoint)
+		}
+	}
+}
+
+func TestConfigLimits(t *testing.T) {
+	d := NewDocker(nil)
+	b := New(d)
+	b.Build = &script.Build{Image: "go1.2"}
+	b.Repo = &repo.Repo{Dir: "/var/cache/drone/src/github.com/foo/bar"}
+	b.Limits = Limits{Memory: 512 << 20, MemorySwap: -1, CpuShares: 256}
+
+	conf := d.Config(b)
+	if conf.Memory != b.Limits.Memory {
+		t.Errorf("Expected memory limit %d, got %d", b.Limits.Memory, conf.Memory)
+	}
+	if conf.MemorySwap != -1 {
+		t.Errorf("Expected swap to be disabled, got %d", conf.MemorySwap)
+	}
+	if conf.CpuShares != 256 {
+		t.Errorf("Expected %d CPU shares, got %d", 256, conf.CpuShares)
+	}
+}
+
+func TestBuildScriptSetup(t *testing.T) {
+	b := New(NewDocker(nil))
+	b.Build = &script.Build{Image: "go1.2", Script: []string{"go test"}, Proxy: true}
+	b.Repo = &repo.Repo{Path: "git://github.com/foo/bar.git", Dir: "/var/cache/drone/src/github.com/foo/bar"}
+
+	script := string(b.BuildScript())
+	for _, cmd := range []string{
+		"sudo cp /drone/id_rsa /home/ubuntu/.ssh/id_rsa",
+		"sudo chown -R ubuntu:ubuntu /var/cache/drone",
+		". /drone/proxy.sh",
+	} {
+		if !strings.Contains(script, cmd+"\n") {
+			t.Errorf("Expected build script to contain %q, got:\n%s", cmd, script)
+		}
+	}
+
+	// the setup must run before the repository is cloned
+	if strings.Index(script, ". /drone/proxy.sh") > strings.Index(script, "git clone") {
+		t.Errorf("Expected setup before git clone, got:\n%s", script)
+	}
+}
+
+func TestBuildScriptServices(t *testing.T) {
+	d := NewDocker(nil)
+	d.services = []*docker.Container{
+		{Name: "/drone-0123456789-0", NetworkSettings: &docker.NetworkSettings{
+			IPAddress: "172.17.0.2",
+			Ports:     map[docker.Port][]docker.PortBinding{"3306/tcp": nil},
+		}},
+		{Name: "/drone-0123456789-1", NetworkSettings: &docker.NetworkSettings{
+			IPAddress: "172.17.0.3",
+			Ports:     map[docker.Port][]docker.PortBinding{"3306/tcp": nil},
+		}},
+	}
+	b := New(d)
+	b.Build = &script.Build{Image: "go1.2", Services: []string{"mysql", "mariadb mariadb:5.5 3306"}}
+	b.Repo = &repo.Repo{Path: "git://github.com/foo/bar.git", Dir: "/var/cache/drone/src/github.com/foo/bar"}
+
+	// the services are added to /etc/hosts
+	script := string(b.BuildScript())
+	for _, host := range []string{"'172.17.0.2 mysql'", "'172.17.0.3 mariadb'"} {
+		if !strings.Contains(script, "echo "+host+" | sudo tee -a /etc/hosts") {
+			t.Errorf("Expected build script to add %s to /etc/hosts, got:\n%s", host, script)
+		}
+	}
+
+	// localhost forwarding is disabled by default
+	if strings.Contains(script, "proxy.sh") {
+		t.Errorf("Expected no service proxy by default, got:\n%s", script)
+	}
+	if proxy := string(d.ProxyScript(b)); proxy != "#!/bin/bash\n" {
+		t.Errorf("Expected an empty proxy script, got:\n%s", proxy)
+	}
+
+	// only the first service is forwarded
+	// when two services use the same port
+	b.Build.Proxy = true
+	proxy := string(d.ProxyScript(b))
+	if !strings.Contains(proxy, "TCP:172.17.0.2:3306") || strings.Contains(proxy, "TCP:172.17.0.3:3306") {
+		t.Errorf("Expected port 3306 forwarded to the first service, got:\n%s", proxy)
+	}
+}
@@ -42,14 +42,31 @@ func (b *Buildfile) WriteComment(comment string) {
 // part of the script. The environment variables
 // are not echoed back to the console, and are
 // kept private by default.
+//
+// The value is quoted and exported as-is. Variables
+// with an invalid name are ignored.
 func (b *Buildfile) WriteEnv(key, value string) {
-	b.WriteString(fmt.Sprintf("export %s=%s\n", key, value))
+	if !IsName(key) {
+		return
+	}
+	b.WriteString(fmt.Sprintf("export %s=%s\n", key, Quote(value)))
+}
+
+// WriteEnvExpand exports the environment variable
+// as part of the script, expanding references to
+// other environment variables in the value, such
+// as $PATH:$GOPATH/bin.
+func (b *Buildfile) WriteEnvExpand(key, value string) {
+	if !IsName(key) {
+		return
+	}
+	b.WriteString(fmt.Sprintf("export %s=%s\n", key, QuoteVars(value)))
 }
 
 // WriteHost adds an entry to the /etc/hosts file.
 func (b *Buildfile) WriteHost(mapping string) {
-	b.WriteCmdSilent(fmt.Sprintf("[ -f /usr/bin/sudo ] || echo %q | tee -a /etc/hosts", mapping))
-	b.WriteCmdSilent(fmt.Sprintf("[ -f /usr/bin/sudo ] && echo %q | sudo tee -a /etc/hosts", mapping))
+	b.WriteCmdSilent(fmt.Sprintf("[ -f /usr/bin/sudo ] || echo %s | tee -a /etc/hosts", Quote(mapping)))
+	b.WriteCmdSilent(fmt.Sprintf("[ -f /usr/bin/sudo ] && echo %s | sudo tee -a /etc/hosts", Quote(mapping)))
 }
 
 // every build script starts with the following
@@ -0,0 +1,108 @@
+package buildfile
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Quote returns the string quoted for use as a single
+// word in the build script. The value is never expanded
+// by the shell, so it may safely contain spaces, quotes,
+// dollar signs and backticks.
+//
+// Strings that only contain characters that are not
+// special to the shell are returned unchanged, which
+// keeps the generated script readable.
+func Quote(s string) string {
+	if len(s) == 0 {
+		return "''"
+	}
+	if isSafe(s) {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// QuoteGlob returns the string quoted for use in the build
+// script, but leaves the wildcards *, ? and [ ] unquoted so
+// that the pattern is expanded by the shell.
+func QuoteGlob(s string) string {
+	if len(s) == 0 {
+		return "''"
+	}
+
+	var buf []string
+	var start int
+	for i, c := range s {
+		if !strings.ContainsRune("*?[]", c) {
+			continue
+		}
+		if i > start {
+			buf = append(buf, Quote(s[start:i]))
+		}
+		buf = append(buf, string(c))
+		start = i + 1
+	}
+	if start < len(s) {
+		buf = append(buf, Quote(s[start:]))
+	}
+	return strings.Join(buf, "")
+}
+
+// QuoteVars returns the string quoted for use in the build
+// script, but allows references to environment variables,
+// such as $HOME or ${GOPATH}, to be expanded by the shell.
+// Command substitution, backticks and all other special
+// characters are escaped.
+func QuoteVars(s string) string {
+	if isSafe(s) && len(s) != 0 {
+		return s
+	}
+
+	var buf = []byte{'"'}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\', '`':
+			buf = append(buf, '\\', c)
+		case '$':
+			if !varRegexp.MatchString(s[i:]) {
+				buf = append(buf, '\\')
+			}
+			buf = append(buf, c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	buf = append(buf, '"')
+	return string(buf)
+}
+
+// IsName returns true if the string is a valid
+// environment variable name.
+func IsName(s string) bool {
+	return nameRegexp.MatchString(s)
+}
+
+// regular expression used to match a variable
+// reference, such as $HOME or ${HOME}.
+var varRegexp = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*|\{[A-Za-z_][A-Za-z0-9_]*\})`)
+
+// regular expression used to match a
+// valid environment variable name.
+var nameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// isSafe returns true if the string only contains
+// characters that are not special to the shell.
+func isSafe(s string) bool {
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z',
+			c >= 'A' && c <= 'Z',
+			c >= '0' && c <= '9',
+			strings.ContainsRune("_-.,/:@%+=", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
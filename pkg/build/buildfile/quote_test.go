@@ -0,0 +1,196 @@
+package buildfile
+
+import (
+	"os/exec"
+	"testing"
+)
+
+// hostile values that must be passed through
+// the shell without modification.
+var hostile = []string{
+	"",
+	"foo",
+	"foo bar",
+	"foo  bar",
+	"it's",
+	`"quoted"`,
+	`back\slash`,
+	"$HOME",
+	"${HOME}",
+	"$(touch /tmp/pwned)",
+	"`touch /tmp/pwned`",
+	"foo; rm -rf /",
+	"foo && echo bar",
+	"foo | cat",
+	"a=b=c",
+	"*",
+	"~",
+	"#comment",
+	"new\nline",
+	"tab\tbed",
+	"'; echo '",
+	"!history",
+	"{a,b}",
+}
+
+func TestQuote(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"", "''"},
+		{"foo", "foo"},
+		{"/opt/bin/redeploy.sh", "/opt/bin/redeploy.sh"},
+		{"user@example.com:/srv/app", "user@example.com:/srv/app"},
+		{"foo bar", "'foo bar'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"`id`", "'`id`'"},
+	}
+
+	for _, test := range tests {
+		if got := Quote(test.input); got != test.want {
+			t.Errorf("Quote(%q) = %s, expected %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestQuoteShell(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not installed")
+	}
+
+	for _, value := range hostile {
+		out, err := exec.Command(bash, "-c", "printf %s "+Quote(value)).Output()
+		if err != nil {
+			t.Errorf("Quote(%q) failed in bash: %s", value, err)
+			continue
+		}
+		if string(out) != value {
+			t.Errorf("Quote(%q) was evaluated by bash as %q", value, out)
+		}
+	}
+}
+
+func TestQuoteGlob(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"", "''"},
+		{"build.result", "build.result"},
+		{"dist/*.jar", "dist/*.jar"},
+		{"my dir/*.jar", "'my dir/'*.jar"},
+		{"file[0-9]", "file[0-9]"},
+		{"$(id)*", "'$(id)'*"},
+	}
+
+	for _, test := range tests {
+		if got := QuoteGlob(test.input); got != test.want {
+			t.Errorf("QuoteGlob(%q) = %s, expected %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestQuoteVars(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"", `""`},
+		{"foo", "foo"},
+		{"$PATH:$GOPATH/bin", `"$PATH:$GOPATH/bin"`},
+		{"${HOME}/go", `"${HOME}/go"`},
+		{"$(id)", `"\$(id)"`},
+		{"`id`", "\"\\`id\\`\""},
+		{`say "hi"`, `"say \"hi\""`},
+		{"cost $5", `"cost \$5"`},
+		{"a=b", "a=b"},
+	}
+
+	for _, test := range tests {
+		if got := QuoteVars(test.input); got != test.want {
+			t.Errorf("QuoteVars(%q) = %s, expected %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestQuoteVarsShell(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not installed")
+	}
+
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"$FOO/bin", "foo/bin"},
+		{"${FOO}bar", "foobar"},
+		{"$(echo pwned)", "$(echo pwned)"},
+		{"`echo pwned`", "`echo pwned`"},
+		{"it's \"quoted\"", "it's \"quoted\""},
+		{`back\slash`, `back\slash`},
+		{"a;b|c&d", "a;b|c&d"},
+		{"$", "$"},
+	}
+
+	for _, test := range tests {
+		cmd := exec.Command(bash, "-c", "printf %s "+QuoteVars(test.input))
+		cmd.Env = []string{"FOO=foo"}
+		out, err := cmd.Output()
+		if err != nil {
+			t.Errorf("QuoteVars(%q) failed in bash: %s", test.input, err)
+			continue
+		}
+		if string(out) != test.want {
+			t.Errorf("QuoteVars(%q) was evaluated by bash as %q, expected %q", test.input, out, test.want)
+		}
+	}
+}
+
+func TestWriteEnv(t *testing.T) {
+	var tests = []struct {
+		key   string
+		value string
+		want  string
+	}{
+		{"FOO", "bar", "export FOO=bar\n"},
+		{"FOO", "", "export FOO=''\n"},
+		{"FOO", "a=b", "export FOO=a=b\n"},
+		{"FOO", "p@ss word$1", "export FOO='p@ss word$1'\n"},
+		{"FOO; rm -rf /", "bar", ""},
+		{"", "bar", ""},
+	}
+
+	for _, test := range tests {
+		f := &Buildfile{}
+		f.WriteEnv(test.key, test.value)
+		if got := f.String(); got != test.want {
+			t.Errorf("WriteEnv(%q, %q) = %q, expected %q", test.key, test.value, got, test.want)
+		}
+	}
+}
+
+func TestWriteEnvShell(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not installed")
+	}
+
+	for _, value := range hostile {
+		f := &Buildfile{}
+		f.WriteEnv("VALUE", value)
+		f.WriteString(`printf %s "$VALUE"`)
+
+		out, err := exec.Command(bash, "-c", f.String()).Output()
+		if err != nil {
+			t.Errorf("WriteEnv(%q) failed in bash: %s", value, err)
+			continue
+		}
+		if string(out) != value {
+			t.Errorf("WriteEnv(%q) was evaluated by bash as %q", value, out)
+		}
+	}
+}
@@ -0,0 +1,586 @@
+package build
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/drone/drone/pkg/build/buildfile"
+	"github.com/drone/drone/pkg/build/docker"
+	"github.com/drone/drone/pkg/build/log"
+	"github.com/drone/drone/pkg/build/proxy"
+)
+
+// buildPath is the path in the build container
+// where the build files are copied.
+const buildPath = "/drone"
+
+// Docker is a Runtime that runs the build in a Docker
+// container created from the build image, linked to a
+// container for each service.
+type Docker struct {
+	// Unique identifier of the build, which prefixes
+	// the names of the containers created for it.
+	uid string
+
+	// Temporary directory on the host machine that
+	// contains the build script, proxy.sh, identity
+	// file and local source code, which are copied
+	// into the build container.
+	dir string
+
+	// Image built from the build image and the build
+	// files, if the Docker daemon does not support
+	// copying files into the container.
+	image string
+
+	// Docker container was that created
+	// for this build.
+	container *docker.Run
+
+	// Docker containers created for the
+	// specified services and linked to
+	// this build.
+	services []*docker.Container
+
+	dockerClient *docker.Client
+}
+
+// NewDocker creates a Docker runtime that
+// uses the given Docker client.
+func NewDocker(dockerClient *docker.Client) *Docker {
+	return &Docker{
+		dockerClient: dockerClient,
+	}
+}
+
+func (d *Docker) Setup(b *Builder) error {
+
+	// temp directory to store all files required to
+	// run the build, which are copied into the build
+	// container through the Docker API, so that the
+	// daemon may run on another machine.
+	tmp, err := tmpPath()
+	if err != nil {
+		return err
+	}
+	// the build is registered as running before any
+	// containers are created, so that its containers
+	// are not removed by the reaper.
+	d.uid = createUID()
+	register(d.uid)
+	d.dir = filepath.Join(tmp, d.uid)
+	if err := os.MkdirAll(filepath.Join(d.dir, "drone"), 0755); err != nil {
+		return fmt.Errorf("Failed to create build directory at %s: %s", d.dir, err)
+	}
+
+	// if this is a local repository we should copy
+	// the source code to our temp directory, which
+	// is copied to the repository path.
+	if b.Repo.IsLocal() {
+		// this is where we used to use symlinks. We should
+		// talk to the docker team about this, since copying
+		// the entire repository is slow :(
+		//
+		// see https://github.com/dotcloud/docker/pull/3567
+
+		src := filepath.Join(d.dir, "src")
+		cmd := exec.Command("cp", "-a", b.Repo.Path, src)
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+
+	// start all services required for the build
+	// that will get linked to the container.
+	for i, service := range b.Build.Services {
+		image, err := getImage(service)
+		if err != nil {
+			return err
+		}
+
+		// debugging
+		log.Infof("starting service container %s", b.Build.Services[i])
+
+		// Run the contianer, with the same resource
+		// limits as the build container.
+		conf := docker.Config{Image: image.Tag}
+		setLimits(&conf, b.Limits)
+		name := fmt.Sprintf("%s-%d", d.uid, i)
+		run, err := d.dockerClient.Containers.RunDaemonPortsConfig(name, &conf, image.Ports...)
+		if err != nil {
+			return err
+		}
+
+		// Get the container info
+		info, err := d.dockerClient.Containers.Inspect(run.ID)
+		if err != nil {
+			// on error kill the container since it hasn't yet been
+			// added to the array and would therefore not get
+			// removed in the defer statement.
+			d.dockerClient.Containers.Stop(run.ID, 10)
+			d.dockerClient.Containers.Remove(run.ID)
+			return err
+		}
+
+		// Add the running service to the list
+		d.services = append(d.services, info)
+
+	}
+
+	// write the identity file
+	if err := ioutil.WriteFile(filepath.Join(d.dir, "drone", "id_rsa"), b.Key, 0600); err != nil {
+		return err
+	}
+
+	// write the build script
+	if err := ioutil.WriteFile(filepath.Join(d.dir, "drone", "drone"), b.BuildScript(), 0755); err != nil {
+		return err
+	}
+
+	// write the proxy script
+	if err := ioutil.WriteFile(filepath.Join(d.dir, "drone", "proxy.sh"), d.ProxyScript(b), 0755); err != nil {
+		return err
+	}
+
+	// check for build container (ie bradrydzewski/go:1.2)
+	// and download if it doesn't already exist
+	if _, err := d.dockerClient.Images.Inspect(b.Build.Image); err == docker.ErrNotFound {
+		// download the image if it doesn't exist
+		if err := d.dockerClient.Images.Pull(b.Build.Image); err != nil {
+			return err
+		}
+	}
+
+	// older Docker daemons can not copy files into a
+	// container, in which case an image is built from
+	// the build image and the build files instead.
+	upload, err := d.dockerClient.Supports(docker.ARCHIVEAPIVERSION)
+	if err != nil {
+		return err
+	}
+	if !upload {
+		return d.buildImage(b)
+	}
+	return nil
+}
+
+// buildImage builds an image from the build image that
+// contains the build files, using the build directory
+// as the build context.
+func (d *Docker) buildImage(b *Builder) error {
+	// debugging
+	log.Info("creating build image")
+
+	dockerfile := fmt.Sprintf("FROM %s\nADD drone %s\n", d.Config(b).Image, buildPath)
+	if b.Repo.IsLocal() {
+		dockerfile += fmt.Sprintf("ADD src %s\n", b.Repo.Dir)
+	}
+	if err := ioutil.WriteFile(filepath.Join(d.dir, "Dockerfile"), []byte(dockerfile), 0644); err != nil {
+		return err
+	}
+
+	if err := d.dockerClient.Images.Build(d.uid, d.dir); err != nil {
+		return err
+	}
+	d.image = d.uid
+	return nil
+}
+
+// Teardown is a helper function that we can use to
+// stop and remove the build container, the supporting
+// service containers, and the temporary build directory.
+func (d *Docker) Teardown(b *Builder) error {
+
+	// stop and destroy the container
+	if d.container != nil {
+
+		// debugging
+		log.Info("removing build container")
+
+		// stop the container, ignore error message
+		d.dockerClient.Containers.Stop(d.container.ID, 15)
+
+		// remove the container, ignore error message
+		if err := d.dockerClient.Containers.Remove(d.container.ID); err != nil {
+			log.Errf("failed to delete build container %s", d.container.ID)
+		}
+	}
+
+	// stop and destroy the container services
+	for i, container := range d.services {
+		// debugging
+		log.Infof("removing service container %s", b.Build.Services[i])
+
+		// stop the service container, ignore the error
+		d.dockerClient.Containers.Stop(container.ID, 15)
+
+		// remove the service container, ignore the error
+		if err := d.dockerClient.Containers.Remove(container.ID); err != nil {
+			log.Errf("failed to delete service container %s", container.ID)
+		}
+	}
+
+	// remove the image built for the build, if any
+	if len(d.image) != 0 {
+		if _, err := d.dockerClient.Images.Remove(d.image); err != nil {
+			log.Errf("failed to delete build image %s", d.image)
+		}
+	}
+
+	// remove the build files
+	if len(d.dir) != 0 {
+		if err := os.RemoveAll(d.dir); err != nil {
+			log.Errf("failed to delete build directory %s. %s", d.dir, err.Error())
+		}
+	}
+
+	unregister(d.uid)
+	return nil
+}
+
+func (d *Docker) Run(b *Builder) (int, error) {
+	// create and run the container directly from
+	// the build image. The build files are copied
+	// into the container before it is started.
+	conf := d.Config(b)
+	if len(d.image) != 0 {
+		conf.Image = d.image
+	}
+	host := docker.HostConfig{
+		Privileged: false,
+	}
+
+	// link service containers
+	for i, service := range d.services {
+		image, err := getImage(b.Build.Services[i])
+		if err != nil {
+			return 1, err
+		}
+		// link the service container to our
+		// build container.
+		host.Links = append(host.Links, service.Name[1:]+":"+image.Name)
+	}
+
+	// where are temp files going to go?
+	tmp_path, err := tmpPath()
+	if err != nil {
+		return 1, err
+	}
+
+	// link cached volumes
+	conf.Volumes = make(map[string]struct{})
+	for _, volume := range b.Build.Cache {
+		name := filepath.Clean(b.Repo.Name)
+		branch := filepath.Clean(b.Repo.Branch)
+		volume := filepath.Clean(volume)
+
+		// with Docker, volumes must be an absolute path. If an absolute
+		// path is not provided, then assume it is for the repository
+		// working directory.
+		if strings.HasPrefix(volume, "/") == false {
+			volume = filepath.Join(b.Repo.Dir, volume)
+		}
+
+		// local cache path on the host machine
+		// this path is going to be really long
+		hostpath := filepath.Join(tmp_path, name, branch, volume)
+
+		// check if the volume is created
+		if _, err := os.Stat(hostpath); err != nil {
+			// if does not exist then create
+			os.MkdirAll(hostpath, 0777)
+		}
+
+		host.Binds = append(host.Binds, hostpath+":"+volume)
+		conf.Volumes[volume] = struct{}{}
+
+		// debugging
+		log.Infof("mounting volume %s:%s", hostpath, volume)
+	}
+
+	// create the container from the image
+	run, err := d.dockerClient.Containers.CreateNamed(d.uid, conf)
+	if err != nil {
+		return 1, err
+	}
+
+	// cache instance of docker.Run
+	d.container = run
+
+	// copy the build files into the container, unless
+	// they were added to the image.
+	if len(d.image) == 0 {
+		if err := d.upload(b); err != nil {
+			return 1, err
+		}
+	}
+
+	// attach to the container
+	go func() {
+		d.dockerClient.Containers.Attach(run.ID, &writer{b.Stdout})
+	}()
+
+	// start the container
+	if err := d.dockerClient.Containers.Start(run.ID, &host); err != nil {
+		return 1, err
+	}
+
+	// wait for the container to stop
+	wait, err := d.dockerClient.Containers.Wait(run.ID)
+	if err != nil {
+		return 1, err
+	}
+
+	// a container killed for exceeding its memory limit
+	// would otherwise look like a failing test, so the
+	// reason is written to the build output.
+	if b.Limits.Memory > 0 {
+		info, err := d.dockerClient.Containers.Inspect(run.ID)
+		if err == nil && info.State.OOMKilled {
+			b.BuildState.OOMKilled = true
+			fmt.Fprintf(&writer{b.Stdout}, "\nBuild was killed because it exceeded the memory limit of %d MB\n", b.Limits.Memory>>20)
+			if wait.StatusCode == 0 {
+				wait.StatusCode = 137
+			}
+		}
+	}
+
+	// get the exit code if possible
+	return wait.StatusCode, nil
+}
+
+// upload copies the build files into the build container,
+// streaming the archive as it is written.
+func (d *Docker) upload(b *Builder) error {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeArchive(writer, d.dir, b))
+	}()
+	err := d.dockerClient.Containers.Upload(d.container.ID, "/", reader)
+	reader.Close()
+	return err
+}
+
+// writeArchive writes a tar archive of the build files, to
+// be extracted at the root of the build container. The files
+// in the drone directory are extracted to /drone, and the
+// local source code, if any, to the repository path.
+func writeArchive(w io.Writer, dir string, b *Builder) error {
+	archive := tar.NewWriter(w)
+	if err := addArchive(archive, filepath.Join(dir, "drone"), buildPath); err != nil {
+		return err
+	}
+	if b.Repo.IsLocal() {
+		if err := addArchive(archive, filepath.Join(dir, "src"), b.Repo.Dir); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// addArchive adds the files in the directory to the
+// archive, with the given path in the container.
+func addArchive(archive *tar.Writer, dir, path string) error {
+	return filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(name); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = strings.TrimPrefix(filepath.ToSlash(filepath.Join(path, rel)), "/")
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(archive, file)
+		return err
+	})
+}
+
+// Config returns the configuration of the build container,
+// which is created directly from the build image. The user
+// and environment depend on the image, since all official
+// Drone images use the "ubuntu" user.
+func (d *Docker) Config(b *Builder) *docker.Config {
+	// use the image name the alias refers to, if any, since
+	// the default user depends on the official image name.
+	image := b.Build.Image
+	if alias, ok := builders[image]; ok {
+		image = alias.Tag
+	}
+
+	conf := docker.Config{
+		Image:        image,
+		WorkingDir:   b.Repo.Dir,
+		Entrypoint:   []string{"/bin/bash", "-e", buildPath + "/drone"},
+		AttachStdin:  false,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+
+	switch {
+	case isOfficial(image):
+		// the default user for all official Drone imnage
+		// is the "ubuntu" user, since all build images
+		// inherit from the ubuntu cloud ISO
+		conf.User = "ubuntu"
+		conf.Env = []string{
+			"HOME=/home/ubuntu",
+			"LANG=en_US.UTF-8",
+			"LANGUAGE=en_US:en",
+			"LOGNAME=ubuntu",
+			"TERM=xterm",
+			"SHELL=/bin/bash",
+		}
+	default:
+		// all other images are assumed to use
+		// the root user.
+		conf.User = "root"
+		conf.Env = []string{
+			"HOME=/root",
+			"LANG=en_US.UTF-8",
+			"LANGUAGE=en_US:en",
+			"LOGNAME=root",
+			"TERM=xterm",
+			"SHELL=/bin/bash",
+			"GOPATH=/var/cache/drone",
+		}
+	}
+
+	setLimits(&conf, b.Limits)
+	return &conf
+}
+
+// setLimits applies the memory and CPU
+// limits to the container configuration.
+func setLimits(conf *docker.Config, limits Limits) {
+	conf.Memory = limits.Memory
+	conf.MemorySwap = limits.MemorySwap
+	conf.CpuShares = limits.CpuShares
+}
+
+// isOfficial returns true if the image is
+// an official Drone build image.
+func isOfficial(image string) bool {
+	return strings.HasPrefix(image, "bradrydzewski/") ||
+		strings.HasPrefix(image, "drone/")
+}
+
+// writeSetup writes the commands that prepare the build
+// container, which copy the identity file into the home
+// directory, take ownership of the source directory, add
+// the services to /etc/hosts and start the service proxy.
+func (d *Docker) writeSetup(b *Builder, f *buildfile.Buildfile) {
+	conf := d.Config(b)
+	dir := buildfile.Quote(b.Repo.Dir)
+	key := buildPath + "/id_rsa"
+
+	switch conf.User {
+	case "ubuntu":
+		f.WriteCmdSilent("sudo mkdir -p /home/ubuntu/.ssh " + dir)
+		f.WriteCmdSilent("sudo cp " + key + " /home/ubuntu/.ssh/id_rsa")
+		f.WriteCmdSilent("sudo chown -R ubuntu:ubuntu /home/ubuntu/.ssh")
+		f.WriteCmdSilent("sudo chown -R ubuntu:ubuntu /var/cache/drone")
+		f.WriteCmdSilent("sudo chmod 600 /home/ubuntu/.ssh/id_rsa")
+	default:
+		f.WriteCmdSilent("mkdir -p /root/.ssh " + dir)
+		f.WriteCmdSilent("cp " + key + " /root/.ssh/id_rsa")
+		f.WriteCmdSilent("chmod 600 /root/.ssh/id_rsa")
+		f.WriteCmdSilent("echo 'StrictHostKeyChecking no' > /root/.ssh/config")
+	}
+
+	// services are reachable by the alias of their link,
+	// such as mysql, so the alias is added to /etc/hosts
+	// in case the Docker daemon does not add it.
+	for i, container := range d.services {
+		image, err := getImage(b.Build.Services[i])
+		if err != nil {
+			continue
+		}
+		f.WriteHost(container.NetworkSettings.IPAddress + " " + image.Name)
+	}
+
+	// forwarding the service ports on localhost is
+	// only kept for builds that depend on it.
+	if b.Build.Proxy {
+		f.WriteCmdSilent(". " + buildPath + "/proxy.sh")
+	}
+}
+
+// ProxyScript generates the proxy.sh file that forwards
+// the service ports on localhost to the service containers,
+// if localhost forwarding is enabled for the build.
+//
+// If the service containers are not running, for example
+// when previewing the build with drone script, the ports
+// are forwarded to the link alias of each service instead
+// of its IP address.
+func (d *Docker) ProxyScript(b *Builder) []byte {
+	var proxyfile = proxy.Proxy{}
+	if !b.Build.Proxy {
+		return proxyfile.Bytes()
+	}
+
+	// loop through services so that we can
+	// map ip address to localhost
+	for i, container := range d.services {
+		// create an entry for each port, in order,
+		// so the first service to use a port wins.
+		var ports []string
+		for port := range container.NetworkSettings.Ports {
+			ports = append(ports, port.Port())
+		}
+		sort.Strings(ports)
+
+		for _, port := range ports {
+			if !proxyfile.Set(port, container.NetworkSettings.IPAddress) {
+				log.Infof("port %s of service %s is already forwarded to localhost", port, b.Build.Services[i])
+			}
+		}
+	}
+
+	if len(d.services) == 0 {
+		for _, service := range b.Build.Services {
+			image, err := getImage(service)
+			if err != nil {
+				continue
+			}
+			for _, port := range image.Ports {
+				proxyfile.Set(port, image.Name)
+			}
+		}
+	}
+
+	return proxyfile.Bytes()
+}
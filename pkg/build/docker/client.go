@@ -2,6 +2,8 @@ package docker
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,29 +13,64 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/dotcloud/docker/pkg/term"
 	"github.com/dotcloud/docker/utils"
 )
 
 const (
-	APIVERSION        = 1.9
+	APIVERSION        = "1.12"
+	MINAPIVERSION     = "1.9"
+	ARCHIVEAPIVERSION = "1.20"
 	DEFAULTHTTPPORT   = 4243
 	DEFAULTUNIXSOCKET = "/var/run/docker.sock"
 	DEFAULTPROTOCOL   = "unix"
 	DEFAULTTAG        = "latest"
-	VERSION           = "0.8.0"
 )
 
 // Enables verbose logging to the Terminal window
 var Logging = true
 
-// New creates an instance of the Docker Client
+// DroneVersion is the version of drone sent in the
+// User-Agent of requests to the Docker daemon.
+var DroneVersion = "dev"
+
+// New creates an instance of the Docker Client. The
+// host is read from DOCKER_HOST, and TLS is enabled
+// if DOCKER_TLS_VERIFY is set, using the certificates
+// in DOCKER_CERT_PATH.
 func New() *Client {
 	c := &Client{}
 
 	c.setHost(DEFAULTUNIXSOCKET)
+	c.setTLS()
+
+	c.Images = &ImageService{c}
+	c.Containers = &ContainerService{c}
+	return c
+}
+
+// NewHost creates an instance of the Docker Client that
+// connects to the Docker daemon at the given address, such
+// as tcp://10.0.0.2:4243 or unix:///var/run/docker.sock.
+// TLS is enabled if a certificate path is provided, using
+// the ca.pem, cert.pem and key.pem files in that directory.
+func NewHost(host, certPath string) *Client {
+	c := &Client{}
+
+	c.proto = DEFAULTPROTOCOL
+	c.addr = host
+	if pieces := strings.SplitN(host, "://", 2); len(pieces) == 2 {
+		c.proto = pieces[0]
+		c.addr = pieces[1]
+	}
+	if len(certPath) != 0 {
+		c.tls, c.err = loadTLS(certPath)
+	}
 
 	c.Images = &ImageService{c}
 	c.Containers = &ContainerService{c}
@@ -44,10 +81,36 @@ type Client struct {
 	proto string
 	addr  string
 
+	// TLS configuration used to connect to the
+	// Docker daemon, or nil for plain connections.
+	tls *tls.Config
+
+	// error loading the TLS certificates, which is
+	// returned by every request.
+	err error
+
+	// API version negotiated with the Docker
+	// daemon on the first request, and the API
+	// version of the daemon.
+	version string
+	server  string
+	mu      sync.Mutex
+
 	Images     *ImageService
 	Containers *ContainerService
 }
 
+// Version contains information about the
+// version of the Docker daemon.
+type Version struct {
+	Version    string
+	ApiVersion string
+	GitCommit  string
+	GoVersion  string
+	Os         string
+	Arch       string
+}
+
 var (
 	// Returned if the specified resource does not exist.
 	ErrNotFound = errors.New("Not Found")
@@ -67,6 +130,14 @@ var (
 	// Returned if the caller submits a badly formed request. For example,
 	// the caller can receive this return if you forget a required parameter.
 	ErrBadRequest = errors.New("Bad Request")
+
+	// Returned if the Docker daemon does not support
+	// the minimum API version required by the client.
+	ErrUnsupportedVersion = errors.New("Docker API version is not supported, requires " + MINAPIVERSION + " or higher")
+
+	// Returned if the Docker daemon does not support
+	// copying files into a container.
+	ErrArchiveUnsupported = errors.New("Docker API version does not support copying files into a container, requires " + ARCHIVEAPIVERSION + " or higher")
 )
 
 func (c *Client) setHost(defaultUnixSocket string) {
@@ -91,8 +162,167 @@ func (c *Client) setHost(defaultUnixSocket string) {
 	}
 }
 
+// setTLS enables TLS if DOCKER_TLS_VERIFY is set. The CA
+// certificate, client certificate and key are loaded from
+// ca.pem, cert.pem and key.pem in DOCKER_CERT_PATH, which
+// defaults to ~/.docker.
+func (c *Client) setTLS() {
+	if len(os.Getenv("DOCKER_TLS_VERIFY")) == 0 || os.Getenv("DOCKER_TLS_VERIFY") == "0" {
+		return
+	}
+
+	path := os.Getenv("DOCKER_CERT_PATH")
+	if len(path) == 0 {
+		path = filepath.Join(os.Getenv("HOME"), ".docker")
+	}
+	c.tls, c.err = loadTLS(path)
+}
+
+// loadTLS creates the TLS configuration that verifies the
+// Docker daemon using the CA certificate, and authenticates
+// the client using the client certificate, if present.
+func loadTLS(path string) (*tls.Config, error) {
+	conf := &tls.Config{}
+
+	ca, err := ioutil.ReadFile(filepath.Join(path, "ca.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the Docker CA certificate. %s", err)
+	}
+	conf.RootCAs = x509.NewCertPool()
+	if !conf.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("Unable to parse the Docker CA certificate %s", filepath.Join(path, "ca.pem"))
+	}
+
+	certFile := filepath.Join(path, "cert.pem")
+	keyFile := filepath.Join(path, "key.pem")
+	if _, err := os.Stat(certFile); err == nil {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load the Docker client certificate. %s", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+// dial connects to the Docker daemon, using TLS
+// if enabled. TLS is only used for tcp connections,
+// since unix sockets are local to the host.
+func (c *Client) dial() (net.Conn, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.tls != nil && c.proto == "tcp" {
+		return tls.Dial(c.proto, c.addr, c.tls)
+	}
+	return net.Dial(c.proto, c.addr)
+}
+
+// Version returns the version of the Docker daemon.
+func (c *Client) Version() (*Version, error) {
+	version := Version{}
+	err := c.send("GET", "/version", nil, &version)
+	return &version, err
+}
+
+// url returns the versioned path of the API endpoint. The
+// API version is negotiated with the Docker daemon on the
+// first request, and is the highest version supported by
+// both the client and the daemon.
+func (c *Client) url(path string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.version) == 0 {
+		version, err := c.Version()
+		if err != nil {
+			return "", err
+		}
+		c.version, err = negotiate(version.ApiVersion)
+		if err != nil {
+			return "", err
+		}
+		c.server = version.ApiVersion
+	}
+	return fmt.Sprintf("/v%s%s", c.version, path), nil
+}
+
+// Supports returns true if the Docker daemon supports the
+// API version, which may be higher than the version that
+// is negotiated for all other requests.
+func (c *Client) Supports(version string) (bool, error) {
+	if _, err := c.url("/"); err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return compareVersion(c.server, version) >= 0, nil
+}
+
+// userAgent returns the User-Agent of a request to the
+// versioned path, which identifies the version of drone
+// and the API version negotiated with the daemon.
+func userAgent(path string) string {
+	agent := "Drone/" + DroneVersion
+	if strings.HasPrefix(path, "/v") {
+		if i := strings.Index(path[1:], "/"); i != -1 {
+			agent += " Docker-API/" + path[2:i+1]
+		}
+	}
+	return agent
+}
+
+// negotiate returns the highest API version supported by
+// both the client and the daemon. Older daemons do not
+// report the API version, in which case the minimum
+// version is used.
+func negotiate(server string) (string, error) {
+	switch {
+	case len(server) == 0:
+		return MINAPIVERSION, nil
+	case compareVersion(server, MINAPIVERSION) < 0:
+		return "", ErrUnsupportedVersion
+	case compareVersion(server, APIVERSION) > 0:
+		return APIVERSION, nil
+	}
+	return server, nil
+}
+
+// compareVersion compares two API versions, such as
+// 1.9 and 1.10, and returns -1, 0 or 1.
+func compareVersion(a, b string) int {
+	pa := strings.Split(a, ".")
+	pb := strings.Split(b, ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		}
+	}
+	return 0
+}
+
 // helper function used to make HTTP requests to the Docker daemon.
 func (c *Client) do(method, path string, in, out interface{}) error {
+	path, err := c.url(path)
+	if err != nil {
+		return err
+	}
+	return c.send(method, path, in, out)
+}
+
+// send makes the HTTP request to the unversioned path.
+func (c *Client) send(method, path string, in, out interface{}) error {
 	// if data input is provided, serialize to JSON
 	var payload io.Reader
 	if in != nil {
@@ -104,19 +334,19 @@ func (c *Client) do(method, path string, in, out interface{}) error {
 	}
 
 	// create the request
-	req, err := http.NewRequest(method, fmt.Sprintf("/v%g%s", APIVERSION, path), payload)
+	req, err := http.NewRequest(method, path, payload)
 	if err != nil {
 		return err
 	}
 
 	// set the appropariate headers
 	req.Header = http.Header{}
-	req.Header.Set("User-Agent", "Docker-Client/"+VERSION)
+	req.Header.Set("User-Agent", userAgent(path))
 	req.Header.Set("Content-Type", "application/json")
 
 	// dial the host server
 	req.Host = c.addr
-	dial, err := net.Dial(c.proto, c.addr)
+	dial, err := c.dial()
 	if err != nil {
 		return err
 	}
@@ -157,16 +387,21 @@ func (c *Client) do(method, path string, in, out interface{}) error {
 }
 
 func (c *Client) hijack(method, path string, setRawTerminal bool, out io.Writer) error {
-	req, err := http.NewRequest(method, fmt.Sprintf("/v%g%s", APIVERSION, path), nil)
+	path, err := c.url(path)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, path, nil)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("User-Agent", "Docker-Client/"+VERSION)
+	req.Header.Set("User-Agent", userAgent(path))
 	req.Header.Set("Content-Type", "plain/text")
 	req.Host = c.addr
 
-	dial, err := net.Dial(c.proto, c.addr)
+	dial, err := c.dial()
 	if err != nil {
 		if strings.Contains(err.Error(), "connection refused") {
 			return fmt.Errorf("Can't connect to docker daemon. Is 'docker -d' running on this host?")
@@ -205,24 +440,39 @@ func (c *Client) hijack(method, path string, setRawTerminal bool, out io.Writer)
 }
 
 func (c *Client) stream(method, path string, in io.Reader, out io.Writer, headers http.Header) error {
+	path, err := c.url(path)
+	if err != nil {
+		return err
+	}
+	return c.streamPath(method, path, in, out, headers)
+}
+
+// streamPath makes the streaming HTTP request to the
+// versioned path.
+func (c *Client) streamPath(method, path string, in io.Reader, out io.Writer, headers http.Header) error {
 	if (method == "POST" || method == "PUT") && in == nil {
 		in = bytes.NewReader(nil)
 	}
 
 	// setup the request
-	req, err := http.NewRequest(method, fmt.Sprintf("/v%g%s", APIVERSION, path), in)
+	req, err := http.NewRequest(method, path, in)
 	if err != nil {
 		return err
 	}
 
 	// set default headers
+	if headers == nil {
+		headers = http.Header{}
+	}
 	req.Header = headers
-	req.Header.Set("User-Agent", "Docker-Client/0.6.4")
-	req.Header.Set("Content-Type", "plain/text")
+	req.Header.Set("User-Agent", userAgent(path))
+	if len(req.Header.Get("Content-Type")) == 0 {
+		req.Header.Set("Content-Type", "plain/text")
+	}
 
 	// dial the host server
 	req.Host = c.addr
-	dial, err := net.Dial(c.proto, c.addr)
+	dial, err := c.dial()
 	if err != nil {
 		return err
 	}
@@ -249,6 +499,10 @@ func (c *Client) stream(method, path string, in io.Reader, out io.Writer, header
 	case 400:
 		return ErrBadRequest
 	}
+	if resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Docker responded with %s: %s", resp.Status, body)
+	}
 
 	// If no output we exit now with no errors
 	if out == nil {
@@ -1,8 +1,16 @@
 package docker
 
 import (
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -65,3 +73,176 @@ func TestDefaultTcpHost(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestNewHost(t *testing.T) {
+	var tests = []struct {
+		host  string
+		proto string
+		addr  string
+	}{
+		{"tcp://10.0.0.2:4243", "tcp", "10.0.0.2:4243"},
+		{"unix:///var/run/docker.sock", "unix", "/var/run/docker.sock"},
+		{"/var/run/docker.sock", "unix", "/var/run/docker.sock"},
+	}
+
+	for _, test := range tests {
+		client := NewHost(test.host, "")
+		if client.proto != test.proto || client.addr != test.addr {
+			t.Errorf("Expected host %s to use %s %s, got %s %s", test.host, test.proto, test.addr, client.proto, client.addr)
+		}
+		if client.tls != nil {
+			t.Errorf("Expected host %s without TLS", test.host)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	var tests = []struct {
+		server  string
+		version string
+		err     error
+	}{
+		{"", MINAPIVERSION, nil},
+		{"1.9", "1.9", nil},
+		{"1.10", "1.10", nil},
+		{"1.12", "1.12", nil},
+		{"1.15", APIVERSION, nil},
+		{"1.8", "", ErrUnsupportedVersion},
+	}
+
+	for _, test := range tests {
+		version, err := negotiate(test.server)
+		if version != test.version || err != test.err {
+			t.Errorf("Expected server version %q to negotiate %q, got %q %v", test.server, test.version, version, err)
+		}
+	}
+}
+
+// newTestServer returns a handler that responds to the version
+// handshake with the API version, and records the paths and
+// user agents of all other requests.
+func newTestServer(api string, paths *[]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/version" {
+			fmt.Fprintf(w, `{"Version":"0.11.0","ApiVersion":"%s"}`, api)
+			return
+		}
+		*paths = append(*paths, r.URL.Path+" "+r.Header.Get("User-Agent"))
+		fmt.Fprint(w, `{"Id":"abc"}`)
+	})
+}
+
+func TestVersionHandshake(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(newTestServer("1.11", &paths))
+	defer server.Close()
+
+	client := New()
+	client.proto = "tcp"
+	client.addr = strings.TrimPrefix(server.URL, "http://")
+
+	if _, err := client.Containers.Inspect("abc"); err != nil {
+		t.Fatalf("Expected inspect to succeed, got %s", err)
+	}
+	if _, err := client.Containers.Inspect("abc"); err != nil {
+		t.Fatalf("Expected inspect to succeed, got %s", err)
+	}
+
+	want := "/v1.11/containers/abc/json Drone/" + DroneVersion + " Docker-API/1.11"
+	if len(paths) != 2 || paths[0] != want || paths[1] != want {
+		t.Errorf("Expected requests to %q, got %v", want, paths)
+	}
+}
+
+func TestTLSFromEnv(t *testing.T) {
+	var paths []string
+	server := httptest.NewTLSServer(newTestServer("1.9", &paths))
+	defer server.Close()
+
+	// write the server certificate as the CA certificate
+	dir, err := ioutil.TempDir("", "TestTLSFromEnv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]})
+	ioutil.WriteFile(filepath.Join(dir, "ca.pem"), ca, 0600)
+
+	os.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(server.URL, "https://"))
+	os.Setenv("DOCKER_TLS_VERIFY", "1")
+	os.Setenv("DOCKER_CERT_PATH", dir)
+	defer os.Setenv("DOCKER_HOST", "")
+	defer os.Setenv("DOCKER_TLS_VERIFY", "")
+	defer os.Setenv("DOCKER_CERT_PATH", "")
+
+	client := New()
+	if client.tls == nil {
+		t.Fatalf("Expected TLS to be enabled")
+	}
+	if _, err := client.Containers.Inspect("abc"); err != nil {
+		t.Fatalf("Expected inspect over TLS to succeed, got %s", err)
+	}
+	if len(paths) != 1 || !strings.HasPrefix(paths[0], "/v1.9/containers/abc/json") {
+		t.Errorf("Expected request over TLS, got %v", paths)
+	}
+
+	// a missing CA certificate is returned by every request
+	os.Setenv("DOCKER_CERT_PATH", filepath.Join(dir, "missing"))
+	client = New()
+	if _, err := client.Containers.Inspect("abc"); err == nil {
+		t.Errorf("Expected error with a missing CA certificate")
+	}
+}
+
+func TestTLSUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestTLSUnixSocket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var paths []string
+	listener, err := net.Listen("unix", filepath.Join(dir, "docker.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(newTestServer("1.11", &paths))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	// TLS is not used to connect to a unix socket,
+	// even if it is enabled.
+	client := NewHost("unix://"+filepath.Join(dir, "docker.sock"), "")
+	client.tls = &tls.Config{}
+	if _, err := client.Containers.Inspect("abc"); err != nil {
+		t.Fatalf("Expected inspect over the unix socket to succeed, got %s", err)
+	}
+	if len(paths) != 1 {
+		t.Errorf("Expected request over the unix socket, got %v", paths)
+	}
+}
+
+func TestCreateNamed(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/version" {
+			fmt.Fprint(w, `{"Version":"0.11.0","ApiVersion":"1.11"}`)
+			return
+		}
+		query = r.URL.RawQuery
+		fmt.Fprint(w, `{"Id":"abc"}`)
+	}))
+	defer server.Close()
+
+	client := New()
+	client.proto = "tcp"
+	client.addr = strings.TrimPrefix(server.URL, "http://")
+
+	if _, err := client.Containers.CreateNamed("drone-0123456789", &Config{Image: "ubuntu"}); err != nil {
+		t.Fatalf("Expected create to succeed, got %s", err)
+	}
+	if query != "name=drone-0123456789" {
+		t.Errorf("Expected the container name in the query, got %q", query)
+	}
+}
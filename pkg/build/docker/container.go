@@ -3,6 +3,8 @@ package docker
 import (
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 )
 
 type ContainerService struct {
@@ -23,9 +25,23 @@ func (c *ContainerService) ListAll() ([]*Containers, error) {
 	return containers, err
 }
 
+// List all containers, including the size
+// of their filesystems.
+func (c *ContainerService) ListAllSize() ([]*Containers, error) {
+	containers := []*Containers{}
+	err := c.do("GET", "/containers/json?all=1&size=1", nil, &containers)
+	return containers, err
+}
+
 // Create a Container
 func (c *ContainerService) Create(conf *Config) (*Run, error) {
-	run, err := c.create(conf)
+	return c.CreateNamed("", conf)
+}
+
+// Create a Container with the given name. A
+// name is generated by Docker if it is empty.
+func (c *ContainerService) CreateNamed(name string, conf *Config) (*Run, error) {
+	run, err := c.create(name, conf)
 	switch {
 	// if no error, exit immediately
 	case err == nil:
@@ -44,15 +60,38 @@ func (c *ContainerService) Create(conf *Config) (*Run, error) {
 	}
 
 	// now that we have the image, re-try creation
-	return c.create(conf)
+	return c.create(name, conf)
 }
 
-func (c *ContainerService) create(conf *Config) (*Run, error) {
+func (c *ContainerService) create(name string, conf *Config) (*Run, error) {
+	path := "/containers/create"
+	if len(name) != 0 {
+		path += "?name=" + url.QueryEscape(name)
+	}
+
 	run := Run{}
-	err := c.do("POST", "/containers/create", conf, &run)
+	err := c.do("POST", path, conf, &run)
 	return &run, err
 }
 
+// Upload extracts the tar archive into the directory of the
+// container at the given path, which must exist. The daemon
+// must support API version 1.20 or higher.
+func (c *ContainerService) Upload(id, path string, archive io.Reader) error {
+	supported, err := c.Supports(ARCHIVEAPIVERSION)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return ErrArchiveUnsupported
+	}
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/x-tar")
+	endpoint := fmt.Sprintf("/v%s/containers/%s/archive?path=%s", ARCHIVEAPIVERSION, id, url.QueryEscape(path))
+	return c.streamPath("PUT", endpoint, archive, nil, headers)
+}
+
 // Start the container id
 func (c *ContainerService) Start(id string, conf *HostConfig) error {
 	return c.do("POST", fmt.Sprintf("/containers/%s/start", id), &conf, nil)
@@ -117,7 +156,12 @@ func (c *ContainerService) Run(conf *Config, host *HostConfig, out io.Writer) (*
 
 // Run the container as a Daemon
 func (c *ContainerService) RunDaemon(conf *Config, host *HostConfig) (*Run, error) {
-	run, err := c.Create(conf)
+	return c.RunDaemonNamed("", conf, host)
+}
+
+// Run the container as a Daemon with the given name.
+func (c *ContainerService) RunDaemonNamed(name string, conf *Config, host *HostConfig) (*Run, error) {
+	run, err := c.CreateNamed(name, conf)
 	if err != nil {
 		return nil, err
 	}
@@ -128,8 +172,13 @@ func (c *ContainerService) RunDaemon(conf *Config, host *HostConfig) (*Run, erro
 }
 
 func (c *ContainerService) RunDaemonPorts(image string, ports ...string) (*Run, error) {
-	// setup configuration
-	config := Config{Image: image}
+	return c.RunDaemonPortsConfig("", &Config{Image: image}, ports...)
+}
+
+// Run the container as a Daemon with the given name and
+// configuration, publishing the ports on the host loopback
+// interface.
+func (c *ContainerService) RunDaemonPortsConfig(name string, config *Config, ports ...string) (*Run, error) {
 	config.ExposedPorts = make(map[Port]struct{})
 
 	// host configuration
@@ -143,5 +192,5 @@ func (c *ContainerService) RunDaemonPorts(image string, ports ...string) (*Run,
 	}
 	//127.0.0.1::%s
 	//map[3306/tcp:{}] map[3306/tcp:[{127.0.0.1 }]]
-	return c.RunDaemon(&config, &host)
+	return c.RunDaemonNamed(name, config, &host)
 }
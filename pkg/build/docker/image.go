@@ -57,6 +57,15 @@ func (c *ImageService) List() ([]*Images, error) {
 	return images, err
 }
 
+// List all Images, including intermediate
+// layers, which is used to count the disk
+// space used by the images.
+func (c *ImageService) ListAll() ([]*Images, error) {
+	images := []*Images{}
+	err := c.do("GET", "/images/json?all=1", nil, &images)
+	return images, err
+}
+
 // Create an image, either by pull it from the registry or by importing it.
 func (c *ImageService) Create(image string) error {
 	return c.do("POST", fmt.Sprintf("/images/create?fromImage=%s", image), nil, nil)
@@ -56,6 +56,7 @@ type State struct {
 	Running    bool
 	Pid        int
 	ExitCode   int
+	OOMKilled  bool
 	StartedAt  time.Time
 	FinishedAt time.Time
 	Ghost      bool
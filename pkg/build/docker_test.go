@@ -0,0 +1,185 @@
+package build
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/pkg/build/docker"
+	"github.com/drone/drone/pkg/build/repo"
+	"github.com/drone/drone/pkg/build/script"
+)
+
+// daemon is a fake Docker daemon that records the files
+// copied into the build container, and the host config
+// used to start it.
+type daemon struct {
+	api string
+
+	sync.Mutex
+	files      map[string]string
+	dockerfile string
+	image      string
+	host       *docker.HostConfig
+}
+
+func (d *daemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.Lock()
+	defer d.Unlock()
+
+	path := r.URL.Path
+	if i := strings.Index(path[1:], "/"); strings.HasPrefix(path, "/v") && i != -1 {
+		path = path[i+1:]
+	}
+
+	switch {
+	case path == "/version":
+		fmt.Fprintf(w, `{"Version":"1.8.0","ApiVersion":"%s"}`, d.api)
+	case path == "/containers/create":
+		conf := docker.Config{}
+		json.NewDecoder(r.Body).Decode(&conf)
+		d.image = conf.Image
+		fmt.Fprint(w, `{"Id":"abc"}`)
+	case path == "/containers/abc/archive":
+		if r.URL.Query().Get("path") != "/" || r.Header.Get("Content-Type") != "application/x-tar" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		d.files = readArchive(r.Body)
+	case path == "/build":
+		files := readArchive(r.Body)
+		d.dockerfile = files["Dockerfile"]
+		d.files = files
+	case path == "/containers/abc/start":
+		d.host = &docker.HostConfig{}
+		json.NewDecoder(r.Body).Decode(d.host)
+		w.WriteHeader(http.StatusNoContent)
+	case path == "/containers/abc/attach":
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Type: application/vnd.docker.raw-stream\r\n\r\n"))
+		conn.Close()
+	case path == "/containers/abc/wait":
+		fmt.Fprint(w, `{"StatusCode":0}`)
+	case r.Method == "DELETE" && strings.HasPrefix(path, "/images/"):
+		fmt.Fprint(w, `[]`)
+	case r.Method == "GET" && strings.HasPrefix(path, "/images/"):
+		fmt.Fprint(w, `{}`)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// readArchive returns the contents of the regular
+// files in the tar archive, by name.
+func readArchive(r io.Reader) map[string]string {
+	files := map[string]string{}
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err != nil {
+			return files
+		}
+		if header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA {
+			data, _ := ioutil.ReadAll(archive)
+			files[header.Name] = string(data)
+		}
+	}
+}
+
+// newRemoteBuilder returns a Builder for a local repository
+// that runs on the Docker daemon at the address, which does
+// not share the filesystem of the test.
+func newRemoteBuilder(t *testing.T, addr string) *Builder {
+	src, err := ioutil.TempDir("", "TestDockerRemote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(src, "main.go"), []byte("package main"), 0644)
+
+	b := New(NewDocker(docker.NewHost("tcp://"+addr, "")))
+	b.Build = &script.Build{Image: "go1.2", Script: []string{"go test"}}
+	b.Repo = &repo.Repo{Path: src, Dir: "/var/cache/drone/src/local/app"}
+	b.Key = []byte("private key")
+	b.Timeout = time.Minute
+	b.Stdout = ioutil.Discard
+	return b
+}
+
+func TestDockerRemote(t *testing.T) {
+	d := &daemon{api: "1.20"}
+	server := httptest.NewServer(d)
+	defer server.Close()
+
+	b := newRemoteBuilder(t, strings.TrimPrefix(server.URL, "http://"))
+	defer os.RemoveAll(b.Repo.Path)
+	if err := b.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	// the build files and source are copied into
+	// the container, instead of mounted from the
+	// local filesystem.
+	if d.host == nil {
+		t.Fatalf("Expected the build container to be started")
+	}
+	if len(d.host.Binds) != 0 {
+		t.Errorf("Expected no bind mounts, got %v", d.host.Binds)
+	}
+	if d.image != "bradrydzewski/go:1.2" {
+		t.Errorf("Expected the container to be created from the build image, got %s", d.image)
+	}
+	if d.files["drone/id_rsa"] != "private key" {
+		t.Errorf("Expected the identity file to be copied, got %v", d.files)
+	}
+	if !strings.Contains(d.files["drone/drone"], "go test") {
+		t.Errorf("Expected the build script to be copied, got %v", d.files)
+	}
+	if d.files["var/cache/drone/src/local/app/main.go"] != "package main" {
+		t.Errorf("Expected the local source to be copied, got %v", d.files)
+	}
+}
+
+func TestDockerRemoteBuildImage(t *testing.T) {
+	d := &daemon{api: "1.12"}
+	server := httptest.NewServer(d)
+	defer server.Close()
+
+	b := newRemoteBuilder(t, strings.TrimPrefix(server.URL, "http://"))
+	defer os.RemoveAll(b.Repo.Path)
+	if err := b.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	// older daemons can not copy files into a container,
+	// so the files are added to an image built from the
+	// build image.
+	want := "FROM bradrydzewski/go:1.2\nADD drone /drone\nADD src /var/cache/drone/src/local/app\n"
+	if d.dockerfile != want {
+		t.Errorf("Expected Dockerfile %q, got %q", want, d.dockerfile)
+	}
+	if d.files["drone/id_rsa"] != "private key" || d.files["src/main.go"] != "package main" {
+		t.Errorf("Expected the build files in the build context, got %v", d.files)
+	}
+	if !strings.HasPrefix(d.image, "drone-") {
+		t.Errorf("Expected the container to be created from the built image, got %s", d.image)
+	}
+	if d.host == nil || len(d.host.Binds) != 0 {
+		t.Errorf("Expected no bind mounts, got %v", d.host)
+	}
+}
@@ -1,5 +1,9 @@
 package build
 
+import (
+	"github.com/drone/drone/pkg/build/script"
+)
+
 type image struct {
 	// default ports the service will run on.
 	// for example, 3306 for mysql. Note that a service
@@ -236,3 +240,11 @@ var builders = map[string]*image{
 	"scala2.10.3": {Tag: "bradrydzewski/scala:2.10.3"},
 	"scala2.9.3":  {Tag: "bradrydzewski/scala:2.9.3"},
 }
+
+func init() {
+	// register the known services so that the
+	// build script can report unknown services.
+	for name := range services {
+		script.Services[name] = true
+	}
+}
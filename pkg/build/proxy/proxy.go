@@ -8,18 +8,39 @@ import (
 // bash header
 const header = "#!/bin/bash\n"
 
-// this command string will check if the socat utility
-// exists, and if it does, will proxy connections to
-// the external IP address.
-const command = "[ -x /usr/bin/socat ] && socat TCP-LISTEN:%s,fork TCP:%s:%s &\n"
+// this command string will proxy connections
+// on the local port to the external IP address.
+const command = "socat TCP-LISTEN:%s,fork TCP:%s:%s &\n"
+
+// the socat utility is not installed in every image,
+// so the proxy is only started if it exists, and a
+// warning is printed otherwise.
+const (
+	check   = "if [ -x /usr/bin/socat ]; then\n"
+	warning = "else\necho \"socat is not installed in the build image, service ports are not forwarded to localhost\"\nfi\n"
+)
 
 // Proxy stores proxy configuration details mapping
 // a local port to an external IP address with the
-// same port number.
-type Proxy map[string]string
+// same port number, in the order they were added.
+type Proxy []forward
+
+type forward struct {
+	port string
+	ip   string
+}
 
-func (p Proxy) Set(port, ip string) {
-	p[port] = ip
+// Set forwards the local port to the IP address. It
+// returns false if the port is already forwarded, since
+// only one service can listen on each local port.
+func (p *Proxy) Set(port, ip string) bool {
+	for _, f := range *p {
+		if f.port == port {
+			return false
+		}
+	}
+	*p = append(*p, forward{port, ip})
+	return true
 }
 
 // String converts the proxy configuration details
@@ -27,10 +48,15 @@ func (p Proxy) Set(port, ip string) {
 func (p Proxy) String() string {
 	var buf bytes.Buffer
 	buf.WriteString(header)
-	for port, ip := range p {
-		buf.WriteString(fmt.Sprintf(command, port, ip, port))
+	if len(p) == 0 {
+		return buf.String()
 	}
 
+	buf.WriteString(check)
+	for _, f := range p {
+		buf.WriteString(fmt.Sprintf(command, f.port, f.ip, f.port))
+	}
+	buf.WriteString(warning)
 	return buf.String()
 }
 
@@ -14,8 +14,12 @@ func TestProxy(t *testing.T) {
 	b := p.Bytes()
 
 	expected := `#!/bin/bash
-[ -x /usr/bin/socat ] && socat TCP-LISTEN:8080,fork TCP:172.1.4.5:8080 &
-[ -x /usr/bin/socat ] && socat TCP-LISTEN:8000,fork TCP:172.1.3.1:8000 &
+if [ -x /usr/bin/socat ]; then
+socat TCP-LISTEN:8080,fork TCP:172.1.4.5:8080 &
+socat TCP-LISTEN:8000,fork TCP:172.1.3.1:8000 &
+else
+echo "socat is not installed in the build image, service ports are not forwarded to localhost"
+fi
 `
 	if string(b) != expected {
 		t.Errorf("Invalid proxy \n%s", expected)
@@ -30,3 +34,18 @@ func TestProxy(t *testing.T) {
 		t.Errorf("Invalid proxy \n%s", expected)
 	}
 }
+
+func TestProxyCollision(t *testing.T) {
+	// the first service to use a port
+	// is forwarded to localhost.
+	p := Proxy{}
+	if !p.Set("3306", "172.1.4.5") {
+		t.Errorf("Expected port 3306 to be forwarded")
+	}
+	if p.Set("3306", "172.1.3.1") {
+		t.Errorf("Expected port 3306 to be forwarded once")
+	}
+	if len(p) != 1 || p[0].ip != "172.1.4.5" {
+		t.Errorf("Expected port 3306 forwarded to 172.1.4.5, got %v", p)
+	}
+}
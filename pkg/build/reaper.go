@@ -0,0 +1,207 @@
+package build
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drone/drone/pkg/build/docker"
+	"github.com/drone/drone/pkg/build/log"
+)
+
+// uidPattern matches the unique identifier created by
+// createUID, which prefixes the names of the containers
+// and images created for a build.
+var uidPattern = regexp.MustCompile("^drone-[0-9a-f]{10}")
+
+// ReapAge is the minimum age of the containers and images
+// that are reaped. Other processes may run Drone builds on
+// the same Docker host, so anything younger than the longest
+// build timeout may still belong to a running build.
+var ReapAge = 300 * time.Minute
+
+// running stores the unique identifiers of the
+// builds that are running in this process.
+var running = struct {
+	sync.Mutex
+	builds map[string]bool
+}{builds: map[string]bool{}}
+
+// register marks the build as running.
+func register(uid string) {
+	running.Lock()
+	defer running.Unlock()
+	running.builds[uid] = true
+}
+
+// unregister marks the build as finished.
+func unregister(uid string) {
+	running.Lock()
+	defer running.Unlock()
+	delete(running.builds, uid)
+}
+
+// isOrphan returns true if the container or image name was
+// created by Drone for a build that is not running in this
+// process.
+func isOrphan(name string) bool {
+	uid := uidPattern.FindString(strings.TrimPrefix(name, "/"))
+	if len(uid) == 0 {
+		return false
+	}
+
+	running.Lock()
+	defer running.Unlock()
+	return !running.builds[uid]
+}
+
+// Usage is the disk space used by the containers
+// and images on a Docker host.
+type Usage struct {
+	Containers     int
+	ContainersSize int64
+	Images         int
+	ImagesSize     int64
+
+	// Orphans is the number of containers and images
+	// created by Drone that are not tied to a build
+	// running in this process, and are older than
+	// ReapAge.
+	Orphans int
+}
+
+// DiskUsage returns the disk space used by the
+// containers and images on the Docker host.
+func DiskUsage(client *docker.Client) (*Usage, error) {
+	containers, err := client.Containers.ListAllSize()
+	if err != nil {
+		return nil, err
+	}
+	images, err := client.Images.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &Usage{
+		Containers: len(containers),
+		Images:     len(images),
+	}
+	for _, container := range containers {
+		usage.ContainersSize += container.SizeRw
+		if isOrphanContainer(container) {
+			usage.Orphans++
+		}
+	}
+
+	// images are listed with their intermediate
+	// layers, and the size of each image excludes
+	// its parent, so the sizes add up.
+	for _, image := range images {
+		usage.ImagesSize += image.Size
+		if isOrphanImage(image) {
+			usage.Orphans++
+		}
+	}
+	return usage, nil
+}
+
+// Reap removes the containers and images created by Drone
+// that are not tied to a build running in this process. They
+// are left behind if the process exits before the build is
+// torn down, and would otherwise never be removed.
+//
+// Containers and images younger than ReapAge are kept, since
+// they may belong to a build of another process sharing the
+// Docker host. Reap returns the number of containers and
+// images that were removed.
+func Reap(client *docker.Client) (int, error) {
+	containers, err := client.Containers.ListAll()
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int
+	for _, container := range containers {
+		if !isOrphanContainer(container) {
+			continue
+		}
+
+		// debugging
+		log.Infof("removing orphaned container %s", container.Names[0])
+
+		// stop the container, ignore the error
+		client.Containers.Stop(container.ID, 15)
+		if err := client.Containers.Remove(container.ID); err != nil {
+			log.Errf("failed to delete orphaned container %s. %s", container.ID, err)
+			continue
+		}
+		removed++
+	}
+
+	// images are removed after the containers,
+	// since an image cannot be removed while a
+	// container uses it.
+	images, err := client.Images.List()
+	if err != nil {
+		return removed, err
+	}
+	for _, image := range images {
+		if !isExpired(image.Created) {
+			continue
+		}
+		for _, tag := range image.RepoTags {
+			if !isOrphan(tag) {
+				continue
+			}
+
+			// debugging
+			log.Infof("removing orphaned image %s", tag)
+
+			// the image is removed by tag, since it may
+			// also be tagged by the user.
+			if _, err := client.Images.Remove(tag); err != nil {
+				log.Errf("failed to delete orphaned image %s. %s", tag, err)
+				continue
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// isOrphanContainer returns true if the container was created
+// by Drone for a build that is not running in this process, and
+// is older than ReapAge.
+func isOrphanContainer(container *docker.Containers) bool {
+	if !isExpired(container.Created) {
+		return false
+	}
+	for _, name := range container.Names {
+		if isOrphan(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isOrphanImage returns true if the image was created by
+// Drone for a build that is not running in this process, and
+// is older than ReapAge.
+func isOrphanImage(image *docker.Images) bool {
+	if !isExpired(image.Created) {
+		return false
+	}
+	for _, tag := range image.RepoTags {
+		if isOrphan(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// isExpired returns true if the container or image, created
+// at the given unix time, is older than ReapAge.
+func isExpired(created int64) bool {
+	return time.Since(time.Unix(created, 0)) >= ReapAge
+}
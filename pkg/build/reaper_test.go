@@ -0,0 +1,60 @@
+package build
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drone/drone/pkg/build/docker"
+)
+
+func TestIsOrphan(t *testing.T) {
+	register("drone-0123456789")
+	defer unregister("drone-0123456789")
+
+	var tests = []struct {
+		name   string
+		orphan bool
+	}{
+		{"/drone-0123456789", false},
+		{"/drone-0123456789-0", false},
+		{"/drone-0123456789/mysql", false},
+		{"/drone-abcdef0123", true},
+		{"/drone-abcdef0123-1", true},
+		{"drone-abcdef0123:latest", true},
+		{"/drone", false},
+		{"/drone-server", false},
+		{"/mysql", false},
+		{"bradrydzewski/go:1.2", false},
+	}
+
+	for _, test := range tests {
+		if orphan := isOrphan(test.name); orphan != test.orphan {
+			t.Errorf("Expected orphan %v for %s, got %v", test.orphan, test.name, orphan)
+		}
+	}
+
+	unregister("drone-0123456789")
+	if !isOrphan("/drone-0123456789") {
+		t.Errorf("Expected the container of a finished build to be an orphan")
+	}
+}
+
+func TestIsOrphanContainer(t *testing.T) {
+	old := time.Now().Add(-ReapAge - time.Minute).Unix()
+	recent := time.Now().Add(-time.Minute).Unix()
+
+	// a recent container may belong to a build of
+	// another process that shares the Docker host.
+	if isOrphanContainer(&docker.Containers{Names: []string{"/drone-abcdef0123"}, Created: recent}) {
+		t.Errorf("Expected a recent container to not be an orphan")
+	}
+	if !isOrphanContainer(&docker.Containers{Names: []string{"/drone-abcdef0123"}, Created: old}) {
+		t.Errorf("Expected a container older than ReapAge to be an orphan")
+	}
+	if isOrphanImage(&docker.Images{RepoTags: []string{"drone-abcdef0123:latest"}, Created: recent}) {
+		t.Errorf("Expected a recent image to not be an orphan")
+	}
+	if !isOrphanImage(&docker.Images{RepoTags: []string{"drone-abcdef0123:latest"}, Created: old}) {
+		t.Errorf("Expected an image older than ReapAge to be an orphan")
+	}
+}
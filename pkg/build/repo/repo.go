@@ -3,6 +3,8 @@ package repo
 import (
 	"fmt"
 	"strings"
+
+	"github.com/drone/drone/pkg/build/buildfile"
 )
 
 type Repo struct {
@@ -90,8 +92,10 @@ func (r *Repo) IsGit() bool {
 	return false
 }
 
-// returns commands that can be used in a Dockerfile
-// to clone the repository.
+// returns commands that can be used in the build script
+// to clone the repository. The branch, commit and paths
+// are quoted, since they may contain characters that are
+// special to the shell.
 //
 // TODO we should also enable Mercurial projects and SVN projects
 func (r *Repo) Commands() []string {
@@ -103,21 +107,22 @@ func (r *Repo) Commands() []string {
 	}
 
 	cmds := []string{}
-	cmds = append(cmds, fmt.Sprintf("git clone --depth=%d --recursive --branch=%s %s %s", r.Depth, branch, r.Path, r.Dir))
+	cmds = append(cmds, fmt.Sprintf("git clone --depth=%d --recursive --branch=%s %s %s", r.Depth, buildfile.Quote(branch), buildfile.Quote(r.Path), buildfile.Quote(r.Dir)))
 
 	switch {
 	// if a specific commit is provided then we'll
 	// need to clone it.
 	case len(r.PR) > 0:
 
-		cmds = append(cmds, fmt.Sprintf("git fetch origin +refs/pull/%s/head:refs/remotes/origin/pr/%s", r.PR, r.PR))
-		cmds = append(cmds, fmt.Sprintf("git checkout -qf -b pr/%s origin/pr/%s", r.PR, r.PR))
+		pr := buildfile.Quote(r.PR)
+		cmds = append(cmds, fmt.Sprintf("git fetch origin +refs/pull/%s/head:refs/remotes/origin/pr/%s", pr, pr))
+		cmds = append(cmds, fmt.Sprintf("git checkout -qf -b pr/%s origin/pr/%s", pr, pr))
 		//cmds = append(cmds, fmt.Sprintf("git fetch origin +refs/pull/%s/merge:", r.PR))
 		//cmds = append(cmds, fmt.Sprintf("git checkout -qf %s", "FETCH_HEAD"))
 	// if a specific commit is provided then we'll
 	// need to clone it.
 	case len(r.Commit) > 0:
-		cmds = append(cmds, fmt.Sprintf("git checkout -qf %s", r.Commit))
+		cmds = append(cmds, fmt.Sprintf("git checkout -qf %s", buildfile.Quote(r.Commit)))
 	}
 
 	return cmds
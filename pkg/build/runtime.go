@@ -0,0 +1,52 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/drone/drone/pkg/build/buildfile"
+	"github.com/drone/drone/pkg/build/docker"
+)
+
+const (
+	RuntimeDocker = "docker"
+	RuntimeShell  = "shell"
+)
+
+// Runtime prepares the environment for a build and
+// executes the build script. A Runtime is created for
+// each build, and may store the state of the build,
+// such as the workspace and running services.
+type Runtime interface {
+	// Setup prepares the workspace and starts the
+	// services required by the build.
+	Setup(b *Builder) error
+
+	// Run executes the build script, streaming the
+	// output to the builder's Stdout, and returns the
+	// exit code of the build.
+	Run(b *Builder) (int, error)
+
+	// Teardown stops the build and services, and
+	// removes the workspace.
+	Teardown(b *Builder) error
+}
+
+// setupWriter is implemented by a Runtime that adds
+// commands to the start of the build script to prepare
+// the build environment.
+type setupWriter interface {
+	writeSetup(b *Builder, f *buildfile.Buildfile)
+}
+
+// NewRuntime creates the named Runtime. The Docker
+// client is only used by the docker runtime.
+func NewRuntime(name string, client *docker.Client) (Runtime, error) {
+	switch name {
+	case RuntimeDocker, "":
+		return NewDocker(client), nil
+	case RuntimeShell:
+		return NewShell(), nil
+	default:
+		return nil, fmt.Errorf("Error: unknown runtime %s, expected %s or %s", name, RuntimeDocker, RuntimeShell)
+	}
+}
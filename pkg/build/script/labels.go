@@ -0,0 +1,49 @@
+package script
+
+import (
+	"regexp"
+	"strings"
+)
+
+// label is the format of a host label, such as
+// privileged, highmem or docker-1.0.
+var label = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// IsLabel returns true if the name is a valid host label.
+func IsLabel(name string) bool {
+	return label.MatchString(name)
+}
+
+// MatchLabels returns true if a host with the given labels
+// can run a build that requires the labels. A required label
+// with the ! prefix, such as !privileged, must not be present.
+func MatchLabels(labels, required []string) bool {
+	has := map[string]bool{}
+	for _, name := range labels {
+		has[name] = true
+	}
+
+	for _, name := range required {
+		switch {
+		case strings.HasPrefix(name, "!"):
+			if has[name[1:]] {
+				return false
+			}
+		case !has[name]:
+			return false
+		}
+	}
+	return true
+}
+
+// SplitLabels returns the labels in the comma
+// separated list, such as "privileged, highmem".
+func SplitLabels(list string) []string {
+	var labels []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); len(name) != 0 {
+			labels = append(labels, name)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,51 @@
+package script
+
+import (
+	"testing"
+)
+
+func TestMatchLabels(t *testing.T) {
+	var tests = []struct {
+		labels   []string
+		required []string
+		match    bool
+	}{
+		{nil, nil, true},
+		{[]string{"privileged"}, nil, true},
+		{[]string{"privileged", "highmem"}, []string{"highmem"}, true},
+		{[]string{"highmem"}, []string{"privileged"}, false},
+		{nil, []string{"privileged"}, false},
+		{[]string{"highmem"}, []string{"!privileged"}, true},
+		{[]string{"privileged"}, []string{"!privileged"}, false},
+		{[]string{"privileged", "highmem"}, []string{"highmem", "!privileged"}, false},
+	}
+
+	for _, test := range tests {
+		if match := MatchLabels(test.labels, test.required); match != test.match {
+			t.Errorf("Expected labels %v matching %v to return %v", test.labels, test.required, test.match)
+		}
+	}
+}
+
+func TestSplitLabels(t *testing.T) {
+	labels := SplitLabels(" privileged,highmem, ,")
+	if len(labels) != 2 || labels[0] != "privileged" || labels[1] != "highmem" {
+		t.Errorf("Expected labels [privileged highmem], got %v", labels)
+	}
+	if labels := SplitLabels(""); len(labels) != 0 {
+		t.Errorf("Expected no labels, got %v", labels)
+	}
+}
+
+func TestIsLabel(t *testing.T) {
+	for _, name := range []string{"privileged", "highmem", "docker-1.0", "us_east"} {
+		if !IsLabel(name) {
+			t.Errorf("Expected %q to be a valid label", name)
+		}
+	}
+	for _, name := range []string{"", "!privileged", "high mem", "a,b"} {
+		if IsLabel(name) {
+			t.Errorf("Expected %q to be an invalid label", name)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package script
+
+import (
+	"path"
+	"strings"
+)
+
+// Paths stores the include and exclude patterns used
+// to decide if a set of changed files requires a build.
+//
+// Patterns use shell file name syntax, for example
+// *.go or docs/*.md. A pattern ending in /** matches
+// every file below the directory, and a pattern with
+// no slash is matched against the file name only.
+type Paths struct {
+	// Include lists the patterns of files that
+	// trigger a build. If empty, all files do.
+	Include []string `yaml:"include,omitempty"`
+
+	// Exclude lists the patterns of files that
+	// never trigger a build.
+	Exclude []string `yaml:"exclude,omitempty"`
+}
+
+// Match returns true if any of the changed files is
+// included and not excluded. If the list of changed
+// files is empty, Match always returns true, since
+// the changes are unknown.
+func (p *Paths) Match(files []string) bool {
+	if p == nil || len(files) == 0 {
+		return true
+	}
+
+	for _, file := range files {
+		if len(p.Include) != 0 && !matchAny(p.Include, file) {
+			continue
+		}
+		if matchAny(p.Exclude, file) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// matchAny returns true if the file matches
+// any of the patterns.
+func matchAny(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		if matchPath(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath returns true if the file matches the pattern.
+func matchPath(pattern, file string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	switch {
+	case strings.HasSuffix(pattern, "/**"):
+		dir := strings.TrimSuffix(pattern, "/**")
+		for ; file != "." && file != "/"; file = path.Dir(file) {
+			if ok, _ := path.Match(dir, path.Dir(file)); ok {
+				return true
+			}
+		}
+		return false
+	case !strings.Contains(pattern, "/"):
+		ok, _ := path.Match(pattern, path.Base(file))
+		return ok
+	default:
+		ok, _ := path.Match(pattern, file)
+		return ok
+	}
+}
@@ -0,0 +1,41 @@
+package script
+
+import (
+	"testing"
+)
+
+func TestPathsMatch(t *testing.T) {
+	var tests = []struct {
+		paths *Paths
+		files []string
+		match bool
+	}{
+		// no paths section, or unknown changes, always build
+		{nil, []string{"README.md"}, true},
+		{&Paths{Include: []string{"src/**"}}, nil, true},
+
+		// include patterns
+		{&Paths{Include: []string{"src/**"}}, []string{"src/main.go"}, true},
+		{&Paths{Include: []string{"src/**"}}, []string{"src/pkg/a/b.go"}, true},
+		{&Paths{Include: []string{"src/**"}}, []string{"README.md", "docs/index.md"}, false},
+		{&Paths{Include: []string{"src/**"}}, []string{"srcfoo/main.go"}, false},
+		{&Paths{Include: []string{"*.go"}}, []string{"cmd/drone/drone.go"}, true},
+		{&Paths{Include: []string{"cmd/*/main.go"}}, []string{"cmd/drone/main.go"}, true},
+		{&Paths{Include: []string{"cmd/*/main.go"}}, []string{"cmd/drone/util.go"}, false},
+
+		// exclude patterns
+		{&Paths{Exclude: []string{"*.md"}}, []string{"README.md"}, false},
+		{&Paths{Exclude: []string{"*.md"}}, []string{"README.md", "main.go"}, true},
+		{&Paths{Exclude: []string{"docs/**"}}, []string{"docs/api/index.html"}, false},
+
+		// include and exclude patterns
+		{&Paths{Include: []string{"services/api/**"}, Exclude: []string{"*.md"}}, []string{"services/api/README.md"}, false},
+		{&Paths{Include: []string{"services/api/**"}, Exclude: []string{"*.md"}}, []string{"services/api/README.md", "services/api/main.go"}, true},
+	}
+
+	for _, test := range tests {
+		if got := test.paths.Match(test.files); got != test.match {
+			t.Errorf("Expected paths %+v matching %v to be %v, got %v", test.paths, test.files, test.match, got)
+		}
+	}
+}
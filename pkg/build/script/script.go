@@ -70,6 +70,22 @@ type Build struct {
 	// linked to the build environment.
 	Services []string
 
+	// Proxy forwards the service ports on localhost to
+	// the service containers, for builds that connect to
+	// localhost instead of the service hostname. It
+	// requires socat in the build image.
+	Proxy bool
+
+	// Downstream lists the repositories, for example
+	// github.com/foo/bar, that should be built after
+	// a successful build.
+	Downstream []string
+
+	// Labels lists the labels a host must have to run
+	// the build, such as privileged or highmem. A label
+	// with the ! prefix must not be present on the host.
+	Labels []string
+
 	Deploy        *deploy.Deploy       `yaml:"deploy,omitempty"`
 	Publish       *publish.Publish     `yaml:"publish,omitempty"`
 	Notifications *notify.Notification `yaml:"notify,omitempty"`
@@ -77,6 +93,10 @@ type Build struct {
 	// Git specified git-specific parameters, such as
 	// the clone depth and path
 	Git *git.Git `yaml:"git,omitempty"`
+
+	// Paths specifies which changed files trigger a
+	// build, for repositories with many projects.
+	Paths *Paths `yaml:"paths,omitempty"`
 }
 
 // Write adds all the steps to the build script, including
@@ -103,13 +123,15 @@ func (b *Build) Write(f *buildfile.Buildfile) {
 // omitting publish and deploy steps. This is important for
 // pull requests, where deployment would be undesirable.
 func (b *Build) WriteBuild(f *buildfile.Buildfile) {
-	// append environment variables
+	// append environment variables. The value may
+	// reference other variables, such as $HOME, and
+	// may itself contain the "=" character.
 	for _, env := range b.Env {
-		parts := strings.Split(env, "=")
+		parts := strings.SplitN(env, "=", 2)
 		if len(parts) != 2 {
 			continue
 		}
-		f.WriteEnv(parts[0], parts[1])
+		f.WriteEnvExpand(parts[0], parts[1])
 	}
 
 	// append build commands
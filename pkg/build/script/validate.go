@@ -0,0 +1,419 @@
+package script
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/drone/drone/pkg/build/buildfile"
+	"launchpad.net/goyaml"
+)
+
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+)
+
+// Services is the set of service names, such as mysql or
+// redis:2.8, that are known to the build runner. If empty,
+// unknown services are not reported.
+var Services = map[string]bool{}
+
+// Problem describes an error or warning found when
+// validating the build configuration.
+type Problem struct {
+	// Line is the line number in the .drone.yml file,
+	// or 0 if the line is not known.
+	Line int
+
+	// Level is either LevelError or LevelWarning.
+	Level string
+
+	Message string
+}
+
+func (p *Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.Level, p.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", p.Line, p.Level, p.Message)
+}
+
+// Problems is a list of errors and warnings
+// ordered by line number.
+type Problems []*Problem
+
+// HasErrors returns true if the list
+// contains at least one error.
+func (p Problems) HasErrors() bool {
+	for _, problem := range p {
+		if problem.Level == LevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the list of problems,
+// one per line.
+func (p Problems) String() string {
+	var buf []string
+	for _, problem := range p {
+		buf = append(buf, problem.String())
+	}
+	return strings.Join(buf, "\n")
+}
+
+func (p *Problems) add(line int, level, format string, a ...interface{}) {
+	*p = append(*p, &Problem{Line: line, Level: level, Message: fmt.Sprintf(format, a...)})
+}
+
+// Validate parses the build configuration and returns
+// a list of errors and warnings, such as unknown keys,
+// invalid services, malformed environment variables and
+// deployments that are missing required fields.
+func Validate(data []byte, params map[string]string) Problems {
+	var problems Problems
+
+	data = injectParams(data, params)
+	build := Build{}
+	if err := goyaml.Unmarshal(data, &build); err != nil {
+		problems.add(errorLine(err), LevelError, "%s", err)
+		return problems
+	}
+
+	root := scan(data)
+	checkKeys(root.children, reflect.TypeOf(build), "", &problems)
+
+	if len(build.Image) == 0 {
+		problems.add(0, LevelError, "image is required")
+	}
+	if len(build.Script) == 0 {
+		problems.add(root.line("script"), LevelWarning, "script is empty, no build commands will be executed")
+	}
+
+	// verify environment variables use the
+	// KEY=VALUE format.
+	env := root.child("env")
+	for i, v := range build.Env {
+		line := env.itemLine(i, len(build.Env))
+		switch parts := strings.SplitN(v, "=", 2); {
+		case len(parts) != 2:
+			problems.add(line, LevelError, "invalid env %q, expected KEY=VALUE", v)
+		case !buildfile.IsName(parts[0]):
+			problems.add(line, LevelError, "invalid env name %q", parts[0])
+		}
+	}
+
+	// verify services are known, or use the
+	// custom service format.
+	services := root.child("services")
+	for i, v := range build.Services {
+		line := services.itemLine(i, len(build.Services))
+		if err := checkService(v); err != nil {
+			problems.add(line, LevelError, "%s", err)
+		}
+	}
+
+	// verify labels are well formed
+	labels := root.child("labels")
+	for i, v := range build.Labels {
+		if !IsLabel(strings.TrimPrefix(v, "!")) {
+			problems.add(labels.itemLine(i, len(build.Labels)), LevelError, "invalid label %q", v)
+		}
+	}
+
+	// verify deployments have all required fields
+	if build.Deploy != nil {
+		checkPlugins(reflect.ValueOf(build.Deploy).Elem(), root.child("deploy"), &problems)
+	}
+	if build.Publish != nil {
+		checkPlugins(reflect.ValueOf(build.Publish).Elem(), root.child("publish"), &problems)
+	}
+	if build.Notifications != nil {
+		checkPlugins(reflect.ValueOf(build.Notifications).Elem(), root.child("notify"), &problems)
+	}
+
+	sortProblems(problems)
+	return problems
+}
+
+// checkService verifies the service is either a known
+// service, such as mysql:5.5, or a custom service in the
+// format "name image [port,port]".
+func checkService(service string) error {
+	tokens := strings.Split(service, " ")
+	switch len(tokens) {
+	case 1:
+		if len(Services) != 0 && !Services[service] {
+			return fmt.Errorf("unknown service %q", service)
+		}
+	case 2, 3:
+		if len(tokens) == 3 {
+			for _, port := range strings.Split(tokens[2], ",") {
+				if _, err := strconv.Atoi(port); err != nil {
+					return fmt.Errorf("invalid port %q in service %q", port, service)
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("invalid service %q, expected a service name or \"name image [ports]\"", service)
+	}
+	return nil
+}
+
+// checkPlugins calls the Validate method, if implemented,
+// of each plugin configured in the section.
+func checkPlugins(section reflect.Value, n *node, problems *Problems) {
+	for i := 0; i < section.NumField(); i++ {
+		field := section.Field(i)
+		if field.Kind() != reflect.Ptr || field.IsNil() {
+			continue
+		}
+		plugin, ok := field.Interface().(interface {
+			Validate() error
+		})
+		if !ok {
+			continue
+		}
+		if err := plugin.Validate(); err != nil {
+			name := yamlName(section.Type().Field(i))
+			problems.add(n.child(name).line(""), LevelError, "%s: %s", name, err)
+		}
+	}
+}
+
+// checkKeys verifies each key is a known field of the type.
+// Unknown keys are ignored when the build is parsed, so they
+// are reported as warnings, with the closest known field as
+// a suggestion, since the key may be a typo.
+func checkKeys(nodes []*node, t reflect.Type, section string, problems *Problems) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		if name := yamlName(t.Field(i)); name != "-" {
+			fields[name] = t.Field(i).Type
+		}
+	}
+
+	for _, n := range nodes {
+		field, ok := fields[n.key]
+		if ok {
+			checkKeys(n.children, field, strings.TrimPrefix(section+"."+n.key, "."), problems)
+			continue
+		}
+
+		msg := fmt.Sprintf("unknown key %q", n.key)
+		if len(section) != 0 {
+			msg += fmt.Sprintf(" in %s", section)
+		}
+		if match := closest(n.key, fields); len(match) != 0 {
+			msg += fmt.Sprintf(", did you mean %q?", match)
+		}
+		problems.add(n.num, LevelWarning, "%s", msg)
+	}
+}
+
+// yamlName returns the key used to unmarshal the struct
+// field, the yaml tag or the lowercase field name.
+func yamlName(f reflect.StructField) string {
+	if len(f.PkgPath) != 0 {
+		return "-"
+	}
+	name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+	if len(name) == 0 {
+		name = strings.ToLower(f.Name)
+	}
+	return name
+}
+
+// closest returns the field name within an edit distance
+// of two from the key, if any.
+func closest(key string, fields map[string]reflect.Type) string {
+	var match string
+	var best = 3
+	for name := range fields {
+		d := distance(key, name)
+		if d < len(name) && (d < best || (d == best && name < match)) {
+			match, best = name, d
+		}
+	}
+	return match
+}
+
+// distance returns the Levenshtein edit
+// distance between two strings.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minimum(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minimum(a ...int) int {
+	m := a[0]
+	for _, v := range a[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// sortProblems sorts the problems by line
+// number, using a stable insertion sort.
+func sortProblems(p Problems) {
+	for i := 1; i < len(p); i++ {
+		for j := i; j > 0 && p[j].Line < p[j-1].Line; j-- {
+			p[j], p[j-1] = p[j-1], p[j]
+		}
+	}
+}
+
+// regular expression used to extract the
+// line number from a yaml parsing error.
+var errorLineRegexp = regexp.MustCompile(`line (\d+)`)
+
+func errorLine(err error) int {
+	match := errorLineRegexp.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(match[1])
+	return line
+}
+
+// node is a mapping key in the yaml file, used
+// to find the line number of keys and list items.
+type node struct {
+	key    string
+	num    int
+	indent int
+
+	children []*node
+	items    []int
+}
+
+// child returns the child with the given key, or nil.
+func (n *node) child(key string) *node {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.children {
+		if c.key == key {
+			return c
+		}
+	}
+	return nil
+}
+
+// line returns the line number of the child with the
+// given key, or of the node itself if key is empty.
+func (n *node) line(key string) int {
+	if len(key) != 0 {
+		n = n.child(key)
+	}
+	if n == nil {
+		return 0
+	}
+	return n.num
+}
+
+// itemLine returns the line number of the i-th list item,
+// given the number of parsed items. If the list was not
+// written one item per line, the line of the key is used.
+func (n *node) itemLine(i, count int) int {
+	if n == nil {
+		return 0
+	}
+	if len(n.items) != count {
+		return n.num
+	}
+	return n.items[i]
+}
+
+// regular expression used to match a mapping key.
+var keyRegexp = regexp.MustCompile(`^([A-Za-z0-9_.-]+)\s*:(?:\s+(.*))?$`)
+
+// scan returns the tree of mapping keys in the yaml file, using
+// indentation to determine nesting. This is not a full yaml parser
+// and is only used to find line numbers, since the yaml library
+// does not expose them.
+func scan(data []byte) *node {
+	root := &node{indent: -1}
+	stack := []*node{root}
+
+	// indentation of a block scalar (| or >),
+	// whose contents are skipped.
+	block := -1
+
+	for i, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimRight(raw, " \t\r")
+		content := strings.TrimLeft(line, " ")
+		indent := len(line) - len(content)
+		if len(content) == 0 {
+			continue
+		}
+		if block != -1 {
+			if indent > block {
+				continue
+			}
+			block = -1
+		}
+		if strings.HasPrefix(content, "#") || content == "---" {
+			continue
+		}
+
+		var value string
+		switch {
+		case content == "-" || strings.HasPrefix(content, "- "):
+			value = strings.TrimSpace(content[1:])
+			for stack[len(stack)-1].indent > indent {
+				stack = stack[:len(stack)-1]
+			}
+			parent := stack[len(stack)-1]
+			parent.items = append(parent.items, i+1)
+
+		default:
+			match := keyRegexp.FindStringSubmatch(content)
+			if match == nil {
+				continue
+			}
+			for stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			parent := stack[len(stack)-1]
+			child := &node{key: match[1], num: i + 1, indent: indent}
+			parent.children = append(parent.children, child)
+			stack = append(stack, child)
+			value = match[2]
+		}
+
+		switch strings.TrimRight(value, "+-") {
+		case "|", ">":
+			block = indent
+		}
+	}
+	return root
+}
@@ -0,0 +1,99 @@
+package script
+
+import (
+	"strings"
+	"testing"
+)
+
+var validYaml = `
+image: go1.2
+env:
+  - GOPATH=/var/cache/drone
+  - GOFLAGS=-v
+services:
+  - mysql
+  - custom bradrydzewski/custom:latest 80,443
+script:
+  - |
+    echo "key: value"
+    go test ./...
+deploy:
+  heroku:
+    app: safe-island-6261
+notify:
+  email:
+    recipients:
+      - brad@drone.io
+`
+
+func TestValidate(t *testing.T) {
+	Services = map[string]bool{"mysql": true}
+	defer func() { Services = map[string]bool{} }()
+
+	if problems := Validate([]byte(validYaml), nil); len(problems) != 0 {
+		t.Errorf("Expected no problems, got\n%s", problems)
+	}
+
+	var tests = []struct {
+		yaml  string
+		line  int
+		level string
+		text  string
+	}{
+		{"image: go1.2\nscirpt:\n  - go test\n", 2, LevelWarning, `unknown key "scirpt", did you mean "script"?`},
+		{"image: go1.2\ngo: 1.2\nscript:\n  - go test\n", 2, LevelWarning, `unknown key "go", did you mean "git"?`},
+		{"image: go1.2\nscript:\n  - go test\nlanguage: go\n", 4, LevelWarning, `unknown key "language"`},
+		{"image: go1.2\nscript:\n  - go test\nnotify:\n  email:\n    recipient:\n      - brad@drone.io\n", 6, LevelWarning, `unknown key "recipient" in notify.email, did you mean "recipients"?`},
+		{"image: go1.2\nscript:\n  - go test\nenv:\n  - GOPATH=/go\n  - GOFLAGS\n", 6, LevelError, `invalid env "GOFLAGS"`},
+		{"image: go1.2\nscript:\n  - go test\nservices:\n  - mysql\n  - mongo\n", 6, LevelError, `unknown service "mongo"`},
+		{"image: go1.2\nscript:\n  - go test\nservices:\n  - a b c d\n", 5, LevelError, `invalid service "a b c d"`},
+		{"image: go1.2\nscript:\n  - go test\nservices:\n  - custom custom:latest http\n", 5, LevelError, `invalid port "http"`},
+		{"image: go1.2\nscript:\n  - go test\ndeploy:\n  heroku:\n    force: true\n", 5, LevelError, "heroku: app must be provided"},
+		{"image: go1.2\nscript:\n  - go test\ndeploy:\n  modulus:\n    project: foo\n", 5, LevelError, "modulus: token must be provided"},
+		{"image: go1.2\nscript:\n  - go test\nlabels:\n  - highmem\n  - no privileged\n", 6, LevelError, `invalid label "no privileged"`},
+		{"script:\n  - go test\n", 0, LevelError, "image is required"},
+		{"image: go1.2\n", 0, LevelWarning, "script is empty"},
+	}
+
+	for _, test := range tests {
+		var problem *Problem
+		for _, p := range Validate([]byte(test.yaml), nil) {
+			if strings.Contains(p.Message, test.text) {
+				problem = p
+			}
+		}
+		if problem == nil {
+			t.Errorf("Expected problem %q for %q", test.text, test.yaml)
+			continue
+		}
+		if problem.Line != test.line {
+			t.Errorf("Expected problem %q on line %d, got %d", problem.Message, test.line, problem.Line)
+		}
+		if problem.Level != test.level {
+			t.Errorf("Expected problem %q level %s, got %s", problem.Message, test.level, problem.Level)
+		}
+	}
+
+	// yaml syntax errors include the line number
+	problems := Validate([]byte("image: go1.2\nscript:\n  - go test\n  bad: [\n"), nil)
+	if !problems.HasErrors() || problems[0].Line == 0 {
+		t.Errorf("Expected a line numbered yaml error, got\n%s", problems)
+	}
+}
+
+func TestProblemsHasErrors(t *testing.T) {
+	problems := Problems{{Line: 2, Level: LevelWarning, Message: "unknown key"}}
+	if problems.HasErrors() {
+		t.Errorf("Expected warnings to not be errors")
+	}
+
+	problems = append(problems, &Problem{Level: LevelError, Message: "image is required"})
+	if !problems.HasErrors() {
+		t.Errorf("Expected problems to have errors")
+	}
+
+	want := "line 2: warning: unknown key\nerror: image is required"
+	if got := problems.String(); got != want {
+		t.Errorf("Expected problems %q, got %q", want, got)
+	}
+}
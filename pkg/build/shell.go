@@ -0,0 +1,157 @@
+package build
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"github.com/drone/drone/pkg/build/log"
+)
+
+// Shell is a Runtime that runs the build script directly
+// on the host machine, in a temporary workspace. It should
+// only be used for trusted repositories, since the build
+// is not isolated from the host, and pull requests are
+// refused.
+//
+// Services and custom hosts are not supported, since they
+// require a container.
+type Shell struct {
+	// Temporary directory on the host machine that
+	// contains the build script, identity file and
+	// source code.
+	dir string
+
+	// Build script process, while it is running.
+	cmd *exec.Cmd
+}
+
+// NewShell creates a Shell runtime.
+func NewShell() *Shell {
+	return &Shell{}
+}
+
+func (s *Shell) Setup(b *Builder) error {
+	switch {
+	case len(b.Repo.PR) != 0:
+		return fmt.Errorf("Error: pull requests are not supported by the %s runtime", RuntimeShell)
+	case len(b.Build.Services) != 0:
+		return fmt.Errorf("Error: services are not supported by the %s runtime", RuntimeShell)
+	case len(b.Build.Hosts) != 0:
+		return fmt.Errorf("Error: hosts are not supported by the %s runtime", RuntimeShell)
+	}
+
+	tmp, err := tmpPath()
+	if err != nil {
+		return err
+	}
+	s.dir = filepath.Join(tmp, createUID())
+	if err := os.MkdirAll(filepath.Join(s.dir, "tmp"), 0700); err != nil {
+		return fmt.Errorf("Failed to create build directory at %s: %s", s.dir, err)
+	}
+
+	// the repository is cloned, or copied, into the
+	// workspace instead of the absolute path used in
+	// the build container. A copy of the repository is
+	// used so the caller's value is not modified.
+	repo := *b.Repo
+	repo.Dir = filepath.Join(s.dir, "src", repo.Dir)
+	b.Repo = &repo
+
+	if err := os.MkdirAll(filepath.Dir(repo.Dir), 0700); err != nil {
+		return err
+	}
+	if repo.IsLocal() {
+		cmd := exec.Command("cp", "-a", repo.Path, repo.Dir)
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	} else if err := os.MkdirAll(repo.Dir, 0700); err != nil {
+		return err
+	}
+
+	// git uses the identity file through a wrapper script,
+	// instead of overwriting the host's ~/.ssh/id_rsa file.
+	key := filepath.Join(s.dir, "id_rsa")
+	if err := ioutil.WriteFile(key, b.Key, 0600); err != nil {
+		return err
+	}
+	wrapper := fmt.Sprintf("#!/bin/sh\nexec ssh -i %s -o StrictHostKeyChecking=no \"$@\"\n", key)
+	if err := ioutil.WriteFile(filepath.Join(s.dir, "ssh"), []byte(wrapper), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(s.dir, "drone"), b.BuildScript(), 0700)
+}
+
+func (s *Shell) Run(b *Builder) (int, error) {
+	if len(b.Build.Cache) != 0 {
+		log.Infof("cached volumes are not supported by the %s runtime", RuntimeShell)
+	}
+	if b.Limits != (Limits{}) {
+		log.Infof("resource limits are not supported by the %s runtime", RuntimeShell)
+	}
+
+	s.cmd = exec.Command("/bin/bash", "-e", filepath.Join(s.dir, "drone"))
+	s.cmd.Dir = b.Repo.Dir
+	s.cmd.Env = shellEnv(s.dir)
+
+	// the build script runs in its own process group,
+	// so that any processes it starts in the background
+	// are stopped with it.
+	s.cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	// stdout and stderr share the same writer, so
+	// only one goroutine at a time will call Write.
+	out := &writer{b.Stdout}
+	s.cmd.Stdout = out
+	s.cmd.Stderr = out
+
+	err := s.cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok {
+		if status, ok := exit.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus(), nil
+		}
+	}
+	if err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+// Teardown stops the build script and any processes
+// it started, if they are still running, and removes
+// the workspace.
+func (s *Shell) Teardown(b *Builder) error {
+	if s.cmd != nil && s.cmd.Process != nil {
+		syscall.Kill(-s.cmd.Process.Pid, syscall.SIGKILL)
+		s.cmd.Process.Kill()
+	}
+
+	if len(s.dir) != 0 {
+		if err := os.RemoveAll(s.dir); err != nil {
+			log.Errf("failed to delete build directory %s. %s", s.dir, err.Error())
+		}
+	}
+	return nil
+}
+
+// shellEnv returns the environment of the build script.
+// The build starts from a clean environment, instead of
+// the environment of the drone process, which may hold
+// credentials, and HOME is the workspace.
+func shellEnv(dir string) []string {
+	path := os.Getenv("PATH")
+	if len(path) == 0 {
+		path = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	}
+	return []string{
+		"PATH=" + path,
+		"HOME=" + dir,
+		"TMPDIR=" + filepath.Join(dir, "tmp"),
+		"GIT_SSH=" + filepath.Join(dir, "ssh"),
+	}
+}
@@ -0,0 +1,84 @@
+package build
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/pkg/build/repo"
+	"github.com/drone/drone/pkg/build/script"
+)
+
+func TestShellRun(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "drone-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	os.Setenv("DRONE_TMP", filepath.Join(tmp, "builds"))
+	defer os.Setenv("DRONE_TMP", "")
+	os.Setenv("DRONE_SECRET", "secret")
+	defer os.Setenv("DRONE_SECRET", "")
+
+	// create a local repository with a single file
+	src := filepath.Join(tmp, "src")
+	os.MkdirAll(src, 0700)
+	ioutil.WriteFile(filepath.Join(src, "hello.txt"), []byte("hello\n"), 0600)
+
+	var tests = []struct {
+		script []string
+		code   int
+		output string
+	}{
+		{[]string{"cat hello.txt", "echo $DRONE_BRANCH"}, 0, "hello\n$ echo $DRONE_BRANCH\nmaster\n"},
+		{[]string{"exit 3"}, 3, ""},
+		{[]string{"echo secret=$DRONE_SECRET"}, 0, "secret=\n"},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		b := New(NewShell())
+		b.Build = &script.Build{Image: "go1.2", Script: test.script}
+		b.Repo = &repo.Repo{Name: "src", Path: src, Branch: "master", Dir: "/var/cache/drone/src/src"}
+		b.Stdout = &buf
+		b.Timeout = time.Minute
+
+		if err := b.Run(); err != nil {
+			t.Errorf("Expected build to run, got %s", err)
+			continue
+		}
+		if b.BuildState.ExitCode != test.code {
+			t.Errorf("Expected exit code %d, got %d", test.code, b.BuildState.ExitCode)
+		}
+		if !strings.Contains(buf.String(), test.output) {
+			t.Errorf("Expected output %q, got %q", test.output, buf.String())
+		}
+	}
+
+	// the workspace should be removed
+	if files, _ := ioutil.ReadDir(filepath.Join(tmp, "builds")); len(files) != 0 {
+		t.Errorf("Expected workspace to be removed, found %d files", len(files))
+	}
+}
+
+func TestShellServices(t *testing.T) {
+	b := New(NewShell())
+	b.Build = &script.Build{Image: "go1.2", Services: []string{"redis"}}
+	b.Repo = &repo.Repo{Path: "/tmp"}
+	if err := b.Run(); err == nil {
+		t.Errorf("Expected error running services with the shell runtime")
+	}
+}
+
+func TestShellPullRequest(t *testing.T) {
+	b := New(NewShell())
+	b.Build = &script.Build{Image: "go1.2"}
+	b.Repo = &repo.Repo{Path: "/tmp", PR: "1"}
+	if err := b.Run(); err == nil {
+		t.Errorf("Expected error running pull requests with the shell runtime")
+	}
+}
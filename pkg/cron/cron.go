@@ -0,0 +1,216 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// descriptors are shorthand aliases for commonly
+// used cron expressions.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@nightly":  "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// bounds defines the minimum and maximum value
+// allowed for a field in the cron expression.
+type bounds struct {
+	min, max int
+}
+
+var (
+	minutes = bounds{0, 59}
+	hours   = bounds{0, 23}
+	doms    = bounds{1, 31}
+	months  = bounds{1, 12}
+	dows    = bounds{0, 7}
+)
+
+// daysIn is the maximum number of days in each month,
+// including the 29th of February in leap years.
+var daysIn = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// Expression represents a parsed cron expression in
+// the standard five field format:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Each field may be a wildcard (*), a single value,
+// a range (1-5), a list (1,3,5) or a step (*/15).
+type Expression struct {
+	minute map[int]bool
+	hour   map[int]bool
+	dom    map[int]bool
+	month  map[int]bool
+	dow    map[int]bool
+
+	// when either the day-of-month or day-of-week field
+	// is restricted, cron matches if either field matches.
+	domStar bool
+	dowStar bool
+}
+
+// Parse parses the cron expression and returns
+// an Expression that can be evaluated, or an error
+// if the expression is invalid.
+func Parse(spec string) (*Expression, error) {
+	spec = strings.TrimSpace(spec)
+	if alias, ok := descriptors[spec]; ok {
+		spec = alias
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Invalid cron expression %q, expected 5 fields", spec)
+	}
+
+	var err error
+	expr := Expression{}
+	if expr.minute, err = parseField(fields[0], minutes); err != nil {
+		return nil, err
+	}
+	if expr.hour, err = parseField(fields[1], hours); err != nil {
+		return nil, err
+	}
+	if expr.dom, err = parseField(fields[2], doms); err != nil {
+		return nil, err
+	}
+	if expr.month, err = parseField(fields[3], months); err != nil {
+		return nil, err
+	}
+	if expr.dow, err = parseField(fields[4], dows); err != nil {
+		return nil, err
+	}
+	// day of week accepts 7 as an alias for sunday
+	if expr.dow[7] {
+		expr.dow[0] = true
+	}
+	expr.domStar = fields[2] == "*" || fields[2] == "?"
+	expr.dowStar = fields[4] == "*" || fields[4] == "?"
+
+	// an expression that is only restricted by the day of
+	// month never runs if none of the days occur in any of
+	// the months, such as the 30th of February.
+	if expr.dowStar && !expr.occurs() {
+		return nil, fmt.Errorf("Invalid cron expression %q, the day of month never occurs in the month", spec)
+	}
+	return &expr, nil
+}
+
+// occurs returns true if one of the days of month
+// occurs in one of the months of the expression.
+func (e *Expression) occurs() bool {
+	for month := range e.month {
+		for day := range e.dom {
+			if day <= daysIn[month] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Matches returns true if the time t, truncated to
+// the minute, satisfies the cron expression.
+func (e *Expression) Matches(t time.Time) bool {
+	if !e.minute[t.Minute()] || !e.hour[t.Hour()] || !e.month[int(t.Month())] {
+		return false
+	}
+
+	dom := e.dom[t.Day()]
+	dow := e.dow[int(t.Weekday())]
+	switch {
+	case e.domStar && e.dowStar:
+		return true
+	case e.domStar:
+		return dow
+	case e.dowStar:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Next returns the first time after t that satisfies
+// the cron expression. If no time is found within
+// five years a zero time is returned.
+func (e *Expression) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for ; t.Before(end); t = t.Add(time.Minute) {
+		if e.Matches(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseField parses a single field of the cron
+// expression and returns the set of matching values.
+func parseField(field string, b bounds) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		if err := parsePart(part, b, values); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// parsePart parses a single, comma-separated element
+// of a field, such as 1-5 or */10, and adds the
+// matching values to the set.
+func parsePart(part string, b bounds, values map[int]bool) error {
+	step := 1
+	if i := strings.Index(part, "/"); i != -1 {
+		var err error
+		step, err = strconv.Atoi(part[i+1:])
+		if err != nil || step < 1 {
+			return fmt.Errorf("Invalid step in cron field %q", part)
+		}
+		part = part[:i]
+	}
+
+	min, max := b.min, b.max
+	switch {
+	case part == "*" || part == "?":
+		// wildcard, use the full range
+	case strings.Contains(part, "-"):
+		pieces := strings.SplitN(part, "-", 2)
+		var err1, err2 error
+		min, err1 = strconv.Atoi(pieces[0])
+		max, err2 = strconv.Atoi(pieces[1])
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("Invalid range in cron field %q", part)
+		}
+	default:
+		var err error
+		min, err = strconv.Atoi(part)
+		if err != nil {
+			return fmt.Errorf("Invalid value in cron field %q", part)
+		}
+		// a single value with a step, such as 5/15,
+		// runs from the value through the maximum.
+		max = min
+		if step > 1 {
+			max = b.max
+		}
+	}
+
+	if min < b.min || max > b.max || min > max {
+		return fmt.Errorf("Value out of range in cron field %q", part)
+	}
+
+	for i := min; i <= max; i += step {
+		values[i] = true
+	}
+	return nil
+}
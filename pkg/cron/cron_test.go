@@ -0,0 +1,92 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	var valid = []string{
+		"* * * * *",
+		"0 0 * * *",
+		"*/15 * * * *",
+		"0 9-17 * * 1-5",
+		"30 2 1,15 * *",
+		"0 0 * * 7",
+		"0 0 29 2 *",
+		"0 0 30,31 1-2 *",
+		"0 0 30 2 1",
+		"@daily",
+		"@nightly",
+		"@hourly",
+	}
+	for _, spec := range valid {
+		if _, err := Parse(spec); err != nil {
+			t.Errorf("Expected %q to parse, got error %s", spec, err)
+		}
+	}
+
+	var invalid = []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@sometimes",
+		"0 0 30 2 *",
+		"0 0 31 4,6,9,11 *",
+	}
+	for _, spec := range invalid {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Expected %q to fail parsing", spec)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	var tests = []struct {
+		spec  string
+		time  string
+		match bool
+	}{
+		{"* * * * *", "2014-03-10T14:23:00Z", true},
+		{"0 0 * * *", "2014-03-10T00:00:00Z", true},
+		{"0 0 * * *", "2014-03-10T00:01:00Z", false},
+		{"*/15 * * * *", "2014-03-10T14:45:00Z", true},
+		{"*/15 * * * *", "2014-03-10T14:46:00Z", false},
+		{"0 9-17 * * 1-5", "2014-03-10T12:00:00Z", true},  // monday
+		{"0 9-17 * * 1-5", "2014-03-09T12:00:00Z", false}, // sunday
+		{"0 0 * * 7", "2014-03-09T00:00:00Z", true},       // sunday
+		{"0 0 1 * 1", "2014-03-10T00:00:00Z", true},       // monday, not the 1st
+		{"0 0 1 * 1", "2014-03-01T00:00:00Z", true},       // the 1st, not monday
+		{"0 0 1 * 1", "2014-03-11T00:00:00Z", false},
+		{"@hourly", "2014-03-10T14:00:00Z", true},
+	}
+
+	for _, test := range tests {
+		expr, err := Parse(test.spec)
+		if err != nil {
+			t.Errorf("Expected %q to parse, got error %s", test.spec, err)
+			continue
+		}
+		now, _ := time.Parse(time.RFC3339, test.time)
+		if got := expr.Matches(now); got != test.match {
+			t.Errorf("Expected %q matching %s to be %v, got %v", test.spec, test.time, test.match, got)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	expr, _ := Parse("30 2 * * *")
+	now, _ := time.Parse(time.RFC3339, "2014-03-10T14:23:11Z")
+	want, _ := time.Parse(time.RFC3339, "2014-03-11T02:30:00Z")
+	if got := expr.Next(now); !got.Equal(want) {
+		t.Errorf("Expected next run at %s, got %s", want, got)
+	}
+}
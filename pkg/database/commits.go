@@ -47,6 +47,15 @@ WHERE hash = ? AND repo_id = ?
 LIMIT 1
 `
 
+// SQL Queries to retrieve a Commit by hash, branch and repo id.
+const commitFindBranchHashStmt = `
+SELECT id, repo_id, status, started, finished, duration,
+hash, branch, pull_request, author, gravatar, timestamp, message, created, updated
+FROM commits
+WHERE hash = ? AND branch = ? AND repo_id = ?
+LIMIT 1
+`
+
 // SQL Query to retrieve a list of recent commits by user.
 const userCommitRecentStmt = `
 SELECT r.slug, r.host, r.owner, r.name,
@@ -92,7 +101,8 @@ WHERE id IN (
  ORDER BY branch ASC
  `
 
-// SQL Queries to retrieve the latest Commits for each branch.
+// SQL Queries to retrieve the latest Commit for a branch,
+// ignoring skipped Commits that were never built.
 const commitBranchStmt = `
 SELECT id, repo_id, status, started, finished, duration,
 hash, branch, pull_request, author, gravatar, timestamp, message, created, updated
@@ -101,11 +111,26 @@ WHERE id IN (
     SELECT MAX(id)
     FROM commits
     WHERE repo_id = ?
-    AND   branch  = ? 
+    AND   branch  = ?
+    AND   status != 'Skipped'
     GROUP BY branch)
 LIMIT 1
  `
 
+// SQL Queries to retrieve the previous finished commit of a branch.
+const commitBranchPrevStmt = `
+SELECT id, repo_id, status, started, finished, duration,
+hash, branch, pull_request, author, gravatar, timestamp, message, created, updated
+FROM commits
+WHERE repo_id      = ?
+AND   branch       = ?
+AND   pull_request = ?
+AND   id           < ?
+AND   status IN ('Success', 'Failure', 'Error')
+ORDER BY id DESC
+LIMIT 1
+`
+
 // Returns the Commit with the given ID.
 func GetCommit(id int64) (*Commit, error) {
 	commit := Commit{}
@@ -120,6 +145,23 @@ func GetCommitHash(hash string, repo int64) (*Commit, error) {
 	return &commit, err
 }
 
+// Returns the Commit with the given hash and branch.
+func GetCommitBranchHash(branch, hash string, repo int64) (*Commit, error) {
+	commit := Commit{}
+	err := meddler.QueryRow(db, &commit, commitFindBranchHashStmt, hash, branch, repo)
+	return &commit, err
+}
+
+// Returns the most recent finished Commit for the given
+// branch and pull request that was created before the Commit
+// with the given ID, which is used to detect changes in the
+// build status. The pull request is empty for pushes.
+func GetBranchPrev(repo int64, branch, pr string, id int64) (*Commit, error) {
+	commit := Commit{}
+	err := meddler.QueryRow(db, &commit, commitBranchPrevStmt, repo, branch, pr, id)
+	return &commit, err
+}
+
 // Returns the most recent Commit for the given branch.
 func GetBranch(repo int64, branch string) (*Commit, error) {
 	commit := Commit{}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"time"
+
+	. "github.com/drone/drone/pkg/model"
+	"github.com/russross/meddler"
+)
+
+// Name of the Delivery table in the database
+const deliveryTable = "deliveries"
+
+// Number of Deliveries kept for each Repo.
+const deliveryLimit = 50
+
+// SQL Queries to retrieve a list of the most recent
+// Deliveries belonging to a Repo.
+const deliveryStmt = `
+SELECT id, repo_id, commit_id, url, event, attempt, status, error, duration, created
+FROM deliveries
+WHERE repo_id = ?
+ORDER BY id DESC
+LIMIT ?
+`
+
+// SQL Queries to delete all but the most recent
+// Deliveries belonging to a Repo.
+const deliveryPruneStmt = `
+DELETE FROM deliveries
+WHERE repo_id = ?
+AND id NOT IN (
+  SELECT id FROM deliveries
+  WHERE repo_id = ?
+  ORDER BY id DESC
+  LIMIT ?
+)
+`
+
+// Creates a new Delivery, and removes the oldest
+// Deliveries of the Repo beyond the limit.
+func SaveDelivery(delivery *Delivery) error {
+	if delivery.ID == 0 {
+		delivery.Created = time.Now().UTC()
+	}
+	if err := meddler.Save(db, deliveryTable, delivery); err != nil {
+		return err
+	}
+	_, err := db.Exec(deliveryPruneStmt, delivery.RepoID, delivery.RepoID, deliveryLimit)
+	return err
+}
+
+// Returns a list of the most recent Deliveries
+// associated with the specified Repo ID.
+func ListDeliveries(repo int64) ([]*Delivery, error) {
+	var deliveries []*Delivery
+	err := meddler.QueryAll(db, &deliveries, deliveryStmt, repo, deliveryLimit)
+	return deliveries, err
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"time"
+
+	. "github.com/drone/drone/pkg/model"
+	"github.com/russross/meddler"
+)
+
+// Name of the Mail table in the database
+const mailTable = "mails"
+
+// SQL Queries to retrieve a list of the Mails that
+// are due to be delivered, oldest first.
+const mailDueStmt = `
+SELECT id, sender, to_addr, subject, raw, attempts, error, next_attempt, created, updated
+FROM mails
+WHERE next_attempt <= ?
+ORDER BY next_attempt ASC
+LIMIT ?
+`
+
+// SQL Queries to retrieve a list of all queued Mails.
+const mailStmt = `
+SELECT id, sender, to_addr, subject, raw, attempts, error, next_attempt, created, updated
+FROM mails
+ORDER BY id ASC
+`
+
+// SQL Queries to delete a Mail.
+const mailDeleteStmt = `
+DELETE FROM mails WHERE id = ?
+`
+
+// Creates a new Mail, or updates an existing Mail
+// after a failed delivery attempt.
+func SaveMail(mail *Mail) error {
+	if mail.ID == 0 {
+		mail.Created = time.Now().UTC()
+	}
+	mail.Updated = time.Now().UTC()
+	return meddler.Save(db, mailTable, mail)
+}
+
+// Deletes a Mail, once delivered.
+func DeleteMail(id int64) error {
+	_, err := db.Exec(mailDeleteStmt, id)
+	return err
+}
+
+// Returns a list of at most limit Mails that are
+// due to be delivered at the specified time.
+func ListMailDue(now time.Time, limit int) ([]*Mail, error) {
+	var mails []*Mail
+	err := meddler.QueryAll(db, &mails, mailDueStmt, now.UTC(), limit)
+	return mails, err
+}
+
+// Returns a list of all queued Mails.
+func ListMails() ([]*Mail, error) {
+	var mails []*Mail
+	err := meddler.QueryAll(db, &mails, mailStmt)
+	return mails, err
+}
@@ -0,0 +1,25 @@
+package migrate
+
+type Rev4 struct{}
+
+var BuildTrigger = &Rev4{}
+
+func (r *Rev4) Revision() int64 {
+	return 201403101200
+}
+
+func (r *Rev4) Up(op Operation) error {
+	_, err := op.AddColumn("builds", "trigger_type VARCHAR(255)")
+	if err != nil {
+		return err
+	}
+	_, err = op.AddColumn("builds", "triggered_by VARCHAR(255)")
+
+	op.Exec("update builds set trigger_type=?, triggered_by=?", "", "")
+	return err
+}
+
+func (r *Rev4) Down(op Operation) error {
+	_, err := op.DropColumns("builds", []string{"trigger_type", "triggered_by"})
+	return err
+}
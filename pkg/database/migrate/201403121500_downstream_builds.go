@@ -0,0 +1,30 @@
+package migrate
+
+type Rev5 struct{}
+
+var DownstreamBuilds = &Rev5{}
+
+func (r *Rev5) Revision() int64 {
+	return 201403121500
+}
+
+func (r *Rev5) Up(op Operation) error {
+	_, err := op.AddColumn("repos", "downstream VARCHAR(2000)")
+	if err != nil {
+		return err
+	}
+	_, err = op.AddColumn("builds", "upstream VARCHAR(1024)")
+
+	op.Exec("update repos set downstream=?", "")
+	op.Exec("update builds set upstream=?", "")
+	return err
+}
+
+func (r *Rev5) Down(op Operation) error {
+	_, err := op.DropColumns("repos", []string{"downstream"})
+	if err != nil {
+		return err
+	}
+	_, err = op.DropColumns("builds", []string{"upstream"})
+	return err
+}
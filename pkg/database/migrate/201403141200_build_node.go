@@ -0,0 +1,20 @@
+package migrate
+
+type Rev6 struct{}
+
+var BuildNode = &Rev6{}
+
+func (r *Rev6) Revision() int64 {
+	return 201403141200
+}
+
+func (r *Rev6) Up(op Operation) error {
+	_, err := op.AddColumn("builds", "node VARCHAR(1024)")
+	op.Exec("update builds set node=?", "")
+	return err
+}
+
+func (r *Rev6) Down(op Operation) error {
+	_, err := op.DropColumns("builds", []string{"node"})
+	return err
+}
@@ -0,0 +1,30 @@
+package migrate
+
+type Rev7 struct{}
+
+var NodeLabels = &Rev7{}
+
+func (r *Rev7) Revision() int64 {
+	return 201403171200
+}
+
+func (r *Rev7) Up(op Operation) error {
+	_, err := op.AddColumn("nodes", "labels VARCHAR(1024)")
+	if err != nil {
+		return err
+	}
+	_, err = op.AddColumn("builds", "waiting VARCHAR(1024)")
+
+	op.Exec("update nodes set labels=?", "[]")
+	op.Exec("update builds set waiting=?", "")
+	return err
+}
+
+func (r *Rev7) Down(op Operation) error {
+	_, err := op.DropColumns("nodes", []string{"labels"})
+	if err != nil {
+		return err
+	}
+	_, err = op.DropColumns("builds", []string{"waiting"})
+	return err
+}
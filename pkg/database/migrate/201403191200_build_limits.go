@@ -0,0 +1,34 @@
+package migrate
+
+type Rev8 struct{}
+
+var BuildLimits = &Rev8{}
+
+func (r *Rev8) Revision() int64 {
+	return 201403191200
+}
+
+func (r *Rev8) Up(op Operation) error {
+	for _, column := range []string{"build_memory", "build_swap", "build_cpu_shares"} {
+		if _, err := op.AddColumn("settings", column+" INTEGER"); err != nil {
+			return err
+		}
+		op.Exec("update settings set "+column+"=?", 0)
+	}
+	for _, column := range []string{"memory", "swap", "cpu_shares"} {
+		if _, err := op.AddColumn("repos", column+" INTEGER"); err != nil {
+			return err
+		}
+		op.Exec("update repos set "+column+"=?", 0)
+	}
+	return nil
+}
+
+func (r *Rev8) Down(op Operation) error {
+	_, err := op.DropColumns("settings", []string{"build_memory", "build_swap", "build_cpu_shares"})
+	if err != nil {
+		return err
+	}
+	_, err = op.DropColumns("repos", []string{"memory", "swap", "cpu_shares"})
+	return err
+}
@@ -0,0 +1,24 @@
+package migrate
+
+type Rev9 struct{}
+
+var SmtpSecurity = &Rev9{}
+
+func (r *Rev9) Revision() int64 {
+	return 201403241200
+}
+
+func (r *Rev9) Up(op Operation) error {
+	for _, column := range []string{"smtp_security", "smtp_auth"} {
+		if _, err := op.AddColumn("settings", column+" VARCHAR(255)"); err != nil {
+			return err
+		}
+		op.Exec("update settings set "+column+"=?", "")
+	}
+	return nil
+}
+
+func (r *Rev9) Down(op Operation) error {
+	_, err := op.DropColumns("settings", []string{"smtp_security", "smtp_auth"})
+	return err
+}
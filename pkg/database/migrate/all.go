@@ -5,6 +5,12 @@ func (m *Migration) All() *Migration {
 	// List all migrations here
 	m.Add(RenamePrivelegedToPrivileged)
 	m.Add(GitHubEnterpriseSupport)
+	m.Add(BuildTrigger)
+	m.Add(DownstreamBuilds)
+	m.Add(BuildNode)
+	m.Add(NodeLabels)
+	m.Add(BuildLimits)
+	m.Add(SmtpSecurity)
 
 	// m.Add(...)
 	// ...
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"time"
+
+	. "github.com/drone/drone/pkg/model"
+	"github.com/russross/meddler"
+)
+
+// Name of the Node table in the database
+const nodeTable = "nodes"
+
+// SQL Queries to retrieve a list of all Nodes in the system.
+const nodeStmt = `
+SELECT id, address, cert_path, concurrency, labels, created, updated
+FROM nodes
+ORDER BY id ASC
+`
+
+// SQL Queries to retrieve a Node by id.
+const nodeFindStmt = `
+SELECT id, address, cert_path, concurrency, labels, created, updated
+FROM nodes
+WHERE id = ?
+`
+
+// SQL Queries to delete a Node.
+const nodeDeleteStmt = `
+DELETE FROM nodes WHERE id = ?
+`
+
+// Returns the Node with the given ID.
+func GetNode(id int64) (*Node, error) {
+	node := Node{}
+	err := meddler.QueryRow(db, &node, nodeFindStmt, id)
+	return &node, err
+}
+
+// Creates a new Node.
+func SaveNode(node *Node) error {
+	if node.ID == 0 {
+		node.Created = time.Now().UTC()
+	}
+	node.Updated = time.Now().UTC()
+	return meddler.Save(db, nodeTable, node)
+}
+
+// Deletes an existing Node.
+func DeleteNode(id int64) error {
+	_, err := db.Exec(nodeDeleteStmt, id)
+	return err
+}
+
+// Returns a list of all Nodes in the system.
+func ListNodes() ([]*Node, error) {
+	var nodes []*Node
+	err := meddler.QueryAll(db, &nodes, nodeStmt)
+	return nodes, err
+}
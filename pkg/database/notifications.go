@@ -0,0 +1,53 @@
+package database
+
+import (
+	"time"
+
+	. "github.com/drone/drone/pkg/model"
+	"github.com/russross/meddler"
+)
+
+// Name of the Notification table in the database
+const notificationTable = "notifications"
+
+// SQL Queries to retrieve a list of all Notifications
+// sent for a Build.
+const notificationStmt = `
+SELECT id, repo_id, build_id, notifier, target, event, status, error, attempts, created, updated
+FROM notifications
+WHERE build_id = ?
+ORDER BY id ASC
+`
+
+// SQL Queries to retrieve a Notification by id.
+const notificationFindStmt = `
+SELECT id, repo_id, build_id, notifier, target, event, status, error, attempts, created, updated
+FROM notifications
+WHERE id = ?
+LIMIT 1
+`
+
+// Returns the Notification with the given ID.
+func GetNotification(id int64) (*Notification, error) {
+	notification := Notification{}
+	err := meddler.QueryRow(db, &notification, notificationFindStmt, id)
+	return &notification, err
+}
+
+// Creates a new Notification, or updates an
+// existing Notification when it is retried.
+func SaveNotification(notification *Notification) error {
+	if notification.ID == 0 {
+		notification.Created = time.Now().UTC()
+	}
+	notification.Updated = time.Now().UTC()
+	return meddler.Save(db, notificationTable, notification)
+}
+
+// Returns a list of all Notifications sent
+// for the specified Build ID.
+func ListNotifications(build int64) ([]*Notification, error) {
+	var notifications []*Notification
+	err := meddler.QueryAll(db, &notifications, notificationStmt, build)
+	return notifications, err
+}
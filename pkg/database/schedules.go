@@ -0,0 +1,75 @@
+package database
+
+import (
+	"time"
+
+	. "github.com/drone/drone/pkg/model"
+	"github.com/russross/meddler"
+)
+
+// Name of the Schedule table in the database
+const scheduleTable = "schedules"
+
+// SQL Queries to retrieve a list of all Schedules belonging to a Repo.
+const scheduleStmt = `
+SELECT id, repo_id, branch, spec, last_run, created, updated
+FROM schedules
+WHERE repo_id = ?
+ORDER BY branch ASC
+`
+
+// SQL Queries to retrieve a list of all Schedules in the system.
+const scheduleAllStmt = `
+SELECT id, repo_id, branch, spec, last_run, created, updated
+FROM schedules
+ORDER BY id ASC
+`
+
+// SQL Queries to retrieve a Schedule by id.
+const scheduleFindStmt = `
+SELECT id, repo_id, branch, spec, last_run, created, updated
+FROM schedules
+WHERE id = ?
+`
+
+// SQL Queries to delete a Schedule.
+const scheduleDeleteStmt = `
+DELETE FROM schedules WHERE id = ?
+`
+
+// Returns the Schedule with the given ID.
+func GetSchedule(id int64) (*Schedule, error) {
+	schedule := Schedule{}
+	err := meddler.QueryRow(db, &schedule, scheduleFindStmt, id)
+	return &schedule, err
+}
+
+// Creates a new Schedule.
+func SaveSchedule(schedule *Schedule) error {
+	if schedule.ID == 0 {
+		schedule.Created = time.Now().UTC()
+	}
+	schedule.Updated = time.Now().UTC()
+	return meddler.Save(db, scheduleTable, schedule)
+}
+
+// Deletes an existing Schedule.
+func DeleteSchedule(id int64) error {
+	_, err := db.Exec(scheduleDeleteStmt, id)
+	return err
+}
+
+// Returns a list of all Schedules associated
+// with the specified Repo ID.
+func ListSchedules(repo int64) ([]*Schedule, error) {
+	var schedules []*Schedule
+	err := meddler.QueryAll(db, &schedules, scheduleStmt, repo)
+	return schedules, err
+}
+
+// Returns a list of all Schedules in the system.
+func ListAllSchedules() ([]*Schedule, error) {
+	var schedules []*Schedule
+	err := meddler.QueryAll(db, &schedules, scheduleAllStmt)
+	return schedules, err
+}
@@ -112,6 +112,80 @@ CREATE TABLE builds (
 );
 `
 
+// SQL statement to create the Node Table.
+var nodeTableStmt = `
+CREATE TABLE nodes (
+   id          INTEGER PRIMARY KEY AUTOINCREMENT
+  ,address     VARCHAR(1024)
+  ,cert_path   VARCHAR(1024)
+  ,concurrency INTEGER
+  ,created     TIMESTAMP
+  ,updated     TIMESTAMP
+);
+`
+
+// SQL statement to create the Schedule Table.
+var scheduleTableStmt = `
+CREATE TABLE schedules (
+   id       INTEGER PRIMARY KEY AUTOINCREMENT
+  ,repo_id  INTEGER
+  ,branch   VARCHAR(255)
+  ,spec     VARCHAR(255)
+  ,last_run TIMESTAMP
+  ,created  TIMESTAMP
+  ,updated  TIMESTAMP
+);
+`
+
+// SQL statement to create the Delivery Table.
+var deliveryTableStmt = `
+CREATE TABLE deliveries (
+   id        INTEGER PRIMARY KEY AUTOINCREMENT
+  ,repo_id   INTEGER
+  ,commit_id INTEGER
+  ,url       VARCHAR(1024)
+  ,event     VARCHAR(255)
+  ,attempt   INTEGER
+  ,status    INTEGER
+  ,error     VARCHAR(1024)
+  ,duration  INTEGER
+  ,created   TIMESTAMP
+);
+`
+
+// SQL statement to create the Notification Table.
+var notificationTableStmt = `
+CREATE TABLE notifications (
+   id       INTEGER PRIMARY KEY AUTOINCREMENT
+  ,repo_id  INTEGER
+  ,build_id INTEGER
+  ,notifier VARCHAR(255)
+  ,target   VARCHAR(1024)
+  ,event    VARCHAR(255)
+  ,status   VARCHAR(255)
+  ,error    VARCHAR(1024)
+  ,attempts INTEGER
+  ,created  TIMESTAMP
+  ,updated  TIMESTAMP
+);
+`
+
+// SQL statement to create the Mail Table.
+var mailTableStmt = `
+CREATE TABLE mails (
+   id           INTEGER PRIMARY KEY AUTOINCREMENT
+  ,sender       VARCHAR(1024)
+  ,to_addr      VARCHAR(1024)
+  ,subject      VARCHAR(1024)
+  ,raw          BLOB
+  ,attempts     INTEGER
+  ,error        VARCHAR(1024)
+  ,next_attempt TIMESTAMP
+  ,created      TIMESTAMP
+  ,updated      TIMESTAMP
+);
+`
+
 // SQL statement to create the Settings
 var settingsTableStmt = `
 CREATE TABLE settings (
@@ -171,6 +245,22 @@ var buildSlugIndex = `
 CREATE INDEX builds_commit_slug_ix ON builds (commit_id, slug);
 `
 
+var scheduleRepoIndex = `
+CREATE INDEX schedules_repo_ix ON schedules (repo_id);
+`
+
+var deliveryRepoIndex = `
+CREATE INDEX deliveries_repo_ix ON deliveries (repo_id);
+`
+
+var notificationBuildIndex = `
+CREATE INDEX notifications_build_ix ON notifications (build_id);
+`
+
+var mailNextAttemptIndex = `
+CREATE INDEX mails_next_attempt_ix ON mails (next_attempt);
+`
+
 // Load will apply the DDL commands to
 // the provided database.
 func Load(db *sql.DB) error {
@@ -182,6 +272,11 @@ func Load(db *sql.DB) error {
 	db.Exec(repoTableStmt)
 	db.Exec(commitTableStmt)
 	db.Exec(buildTableStmt)
+	db.Exec(scheduleTableStmt)
+	db.Exec(nodeTableStmt)
+	db.Exec(deliveryTableStmt)
+	db.Exec(notificationTableStmt)
+	db.Exec(mailTableStmt)
 	db.Exec(settingsTableStmt)
 
 	db.Exec(memberUniqueIndex)
@@ -194,6 +289,10 @@ func Load(db *sql.DB) error {
 	db.Exec(repoUserIndex)
 	db.Exec(buildCommitIndex)
 	db.Exec(buildSlugIndex)
+	db.Exec(scheduleRepoIndex)
+	db.Exec(deliveryRepoIndex)
+	db.Exec(notificationBuildIndex)
+	db.Exec(mailNextAttemptIndex)
 
 	// migrations for backward compatibility
 	db.Exec("ALTER TABLE settings ADD COLUMN open_invitations BOOLEAN")
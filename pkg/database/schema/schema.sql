@@ -1,3 +1,7 @@
+DROP TABLE IF EXISTS mails;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS deliveries;
+DROP TABLE IF EXISTS schedules;
 DROP TABLE IF EXISTS builds;
 DROP TABLE IF EXISTS commits;
 DROP TABLE IF EXISTS repos;
@@ -61,6 +65,10 @@ CREATE TABLE repos (
 	,public_key  VARCHAR(1024)
 	,private_key VARCHAR(1024)
 	,params      VARCHAR(2000)
+	,downstream  VARCHAR(2000)
+	,memory      INTEGER
+	,swap        INTEGER
+	,cpu_shares  INTEGER
 
 	,created     TIMESTAMP
 	,updated     TIMESTAMP
@@ -97,6 +105,71 @@ CREATE TABLE builds (
 	,created   TIMESTAMP
 	,updated   TIMESTAMP
 	,stdout    BLOB
+	,trigger_type VARCHAR(255)
+	,triggered_by VARCHAR(255)
+	,upstream     VARCHAR(1024)
+	,node         VARCHAR(1024)
+	,waiting      VARCHAR(1024)
+);
+
+CREATE TABLE schedules (
+	 id       INTEGER PRIMARY KEY AUTOINCREMENT
+	,repo_id  INTEGER
+	,branch   VARCHAR(255)
+	,spec     VARCHAR(255)
+	,last_run TIMESTAMP
+	,created  TIMESTAMP
+	,updated  TIMESTAMP
+);
+
+CREATE TABLE nodes (
+	 id          INTEGER PRIMARY KEY AUTOINCREMENT
+	,address     VARCHAR(1024)
+	,cert_path   VARCHAR(1024)
+	,concurrency INTEGER
+	,labels      VARCHAR(1024)
+	,created     TIMESTAMP
+	,updated     TIMESTAMP
+);
+
+CREATE TABLE deliveries (
+	 id        INTEGER PRIMARY KEY AUTOINCREMENT
+	,repo_id   INTEGER
+	,commit_id INTEGER
+	,url       VARCHAR(1024)
+	,event     VARCHAR(255)
+	,attempt   INTEGER
+	,status    INTEGER
+	,error     VARCHAR(1024)
+	,duration  INTEGER
+	,created   TIMESTAMP
+);
+
+CREATE TABLE notifications (
+	 id       INTEGER PRIMARY KEY AUTOINCREMENT
+	,repo_id  INTEGER
+	,build_id INTEGER
+	,notifier VARCHAR(255)
+	,target   VARCHAR(1024)
+	,event    VARCHAR(255)
+	,status   VARCHAR(255)
+	,error    VARCHAR(1024)
+	,attempts INTEGER
+	,created  TIMESTAMP
+	,updated  TIMESTAMP
+);
+
+CREATE TABLE mails (
+	 id           INTEGER PRIMARY KEY AUTOINCREMENT
+	,sender       VARCHAR(1024)
+	,to_addr      VARCHAR(1024)
+	,subject      VARCHAR(1024)
+	,raw          BLOB
+	,attempts     INTEGER
+	,error        VARCHAR(1024)
+	,next_attempt TIMESTAMP
+	,created      TIMESTAMP
+	,updated      TIMESTAMP
 );
 
 CREATE TABLE settings (
@@ -112,9 +185,14 @@ CREATE TABLE settings (
     ,smtp_address     VARCHAR(1024)
     ,smtp_username    VARCHAR(1024)
     ,smtp_password    VARCHAR(1024)
+    ,smtp_security    VARCHAR(255)
+    ,smtp_auth        VARCHAR(255)
     ,hostname         VARCHAR(1024)
     ,scheme           VARCHAR(5)
     ,open_invitations BOOLEAN
+    ,build_memory     INTEGER
+    ,build_swap       INTEGER
+    ,build_cpu_shares INTEGER
 );
 
 CREATE UNIQUE INDEX member_uix       ON members  (team_id, user_id);
@@ -128,3 +206,7 @@ CREATE INDEX commits_repo_ix         ON commits (repo_id);
 CREATE INDEX commits_repo_branch_ix  ON commits (repo_id, branch);
 CREATE INDEX builds_commit_ix        ON builds  (commit_id);
 CREATE INDEX builds_commit_slug_ix   ON builds  (commit_id, slug);
+CREATE INDEX schedules_repo_ix       ON schedules (repo_id);
+CREATE INDEX deliveries_repo_ix      ON deliveries (repo_id);
+CREATE INDEX notifications_build_ix  ON notifications (build_id);
+CREATE INDEX mails_next_attempt_ix   ON mails (next_attempt);
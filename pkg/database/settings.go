@@ -11,7 +11,8 @@ const settingsTable = "settings"
 // SQL Queries to retrieve the system settings
 const settingsStmt = `
 SELECT id, github_key, github_secret, github_domain, github_apiurl, bitbucket_key, bitbucket_secret,
-smtp_server, smtp_port, smtp_address, smtp_username, smtp_password, hostname, scheme, open_invitations
+smtp_server, smtp_port, smtp_address, smtp_username, smtp_password, smtp_security, smtp_auth,
+hostname, scheme, open_invitations, build_memory, build_swap, build_cpu_shares
 FROM settings WHERE id = 1
 `
 
@@ -62,6 +62,8 @@ func TestSaveBbuild(t *testing.T) {
 
 	// update fields
 	build.Status = "Failing"
+	build.Trigger = "manual"
+	build.TriggeredBy = "Brad Rydzewski"
 
 	// update the database
 	if err := database.SaveBuild(build); err != nil {
@@ -85,6 +87,14 @@ func TestSaveBbuild(t *testing.T) {
 	if build.Status != updatedBuild.Status {
 		t.Errorf("Exepected Status %s, got %s", updatedBuild.Status, build.Status)
 	}
+
+	if build.Trigger != updatedBuild.Trigger {
+		t.Errorf("Exepected Trigger %s, got %s", updatedBuild.Trigger, build.Trigger)
+	}
+
+	if build.TriggeredBy != updatedBuild.TriggeredBy {
+		t.Errorf("Exepected TriggeredBy %s, got %s", updatedBuild.TriggeredBy, build.TriggeredBy)
+	}
 }
 
 func TestDeleteBuild(t *testing.T) {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/drone/drone/pkg/database"
+	. "github.com/drone/drone/pkg/model"
 )
 
 func TestGetCommit(t *testing.T) {
@@ -162,3 +163,96 @@ func TestListCommits(t *testing.T) {
 		t.Errorf("Exepected Gravatar %s, got %s", "8c58a0be77ee441bb8f8595b7f1b4e87", commit.Gravatar)
 	}
 }
+
+func TestGetCommitBranchHash(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	commit, err := database.GetCommitBranchHash("master", "4f4c4594be6d6ddbc1c0dd521334f7ecba92b608", 1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if commit.ID != 1 {
+		t.Errorf("Exepected ID %d, got %d", 1, commit.ID)
+	}
+
+	if commit.Branch != "master" {
+		t.Errorf("Exepected Branch %s, got %s", "master", commit.Branch)
+	}
+
+	// the commit was not built for the dev branch
+	if _, err := database.GetCommitBranchHash("dev", "4f4c4594be6d6ddbc1c0dd521334f7ecba92b608", 1); err == nil {
+		t.Errorf("Exepected error getting commit for branch %s", "dev")
+	}
+}
+
+func TestGetBranch(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	// skipped commits should be ignored
+	skipped := Commit{RepoID: 1, Status: "Skipped", Hash: "a0f4dd2ff2ea8bc2fc8ba9e4cd1b1a2e64f8f1e3", Branch: "master"}
+	if err := database.SaveCommit(&skipped); err != nil {
+		t.Error(err)
+	}
+
+	commit, err := database.GetBranch(1, "master")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if commit.ID != 2 {
+		t.Errorf("Exepected ID %d, got %d", 2, commit.ID)
+	}
+
+	if commit.Status != "Failure" {
+		t.Errorf("Exepected Status %s, got %s", "Failure", commit.Status)
+	}
+}
+
+func TestGetBranchPrev(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	// pull requests of the branch should be ignored
+	pull := Commit{RepoID: 1, Status: "Success", Hash: "90f4dd2ff2ea8bc2fc8ba9e4cd1b1a2e64f8f1e3", Branch: "master", PullRequest: "5"}
+	if err := database.SaveCommit(&pull); err != nil {
+		t.Error(err)
+	}
+
+	// running commits should be ignored
+	started := Commit{RepoID: 1, Status: "Started", Hash: "a0f4dd2ff2ea8bc2fc8ba9e4cd1b1a2e64f8f1e3", Branch: "master"}
+	if err := database.SaveCommit(&started); err != nil {
+		t.Error(err)
+	}
+	current := Commit{RepoID: 1, Status: "Started", Hash: "b0f4dd2ff2ea8bc2fc8ba9e4cd1b1a2e64f8f1e3", Branch: "master"}
+	if err := database.SaveCommit(&current); err != nil {
+		t.Error(err)
+	}
+
+	commit, err := database.GetBranchPrev(1, "master", "", current.ID)
+	if err != nil {
+		t.Error(err)
+	}
+	if commit.ID != 2 {
+		t.Errorf("Exepected ID %d, got %d", 2, commit.ID)
+	}
+	if commit.Status != "Failure" {
+		t.Errorf("Exepected Status %s, got %s", "Failure", commit.Status)
+	}
+
+	// the previous build of the pull request
+	commit, err = database.GetBranchPrev(1, "master", "5", current.ID)
+	if err != nil {
+		t.Error(err)
+	}
+	if commit.ID != pull.ID {
+		t.Errorf("Exepected ID %d, got %d", pull.ID, commit.ID)
+	}
+
+	// the first commit has no previous commit
+	if _, err := database.GetBranchPrev(1, "master", "", 1); err == nil {
+		t.Errorf("Exepected error getting previous commit for commit %d", 1)
+	}
+}
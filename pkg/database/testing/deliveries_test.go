@@ -0,0 +1,67 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/drone/drone/pkg/database"
+	. "github.com/drone/drone/pkg/model"
+)
+
+func TestListDeliveries(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	// get the deliveries for the repo
+	deliveries, err := database.ListDeliveries(1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// verify deliveries count
+	if len(deliveries) != 2 {
+		t.Fatalf("Exepected %d deliveries in list, got %d", 2, len(deliveries))
+	}
+
+	// the most recent delivery is listed first
+	if deliveries[0].Attempt != 2 {
+		t.Errorf("Exepected Attempt %d, got %d", 2, deliveries[0].Attempt)
+	}
+
+	if deliveries[0].Status != 200 {
+		t.Errorf("Exepected Status %d, got %d", 200, deliveries[0].Status)
+	}
+
+	if deliveries[1].IsSuccess() {
+		t.Errorf("Exepected the first attempt to have failed")
+	}
+}
+
+func TestSaveDeliveryLimit(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	for i := 0; i < 60; i++ {
+		delivery := Delivery{RepoID: 2, URL: "http://example.com/hook", Event: "success", Attempt: 1, Status: 200}
+		if err := database.SaveDelivery(&delivery); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// only the most recent deliveries are kept
+	deliveries, err := database.ListDeliveries(2)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(deliveries) != 50 {
+		t.Errorf("Exepected %d deliveries in list, got %d", 50, len(deliveries))
+	}
+
+	// deliveries of other repos are not removed
+	deliveries, err = database.ListDeliveries(1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(deliveries) != 2 {
+		t.Errorf("Exepected %d deliveries in list, got %d", 2, len(deliveries))
+	}
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drone/drone/pkg/database"
+	. "github.com/drone/drone/pkg/model"
+)
+
+func TestListMailDue(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	now := time.Now().UTC()
+	database.SaveMail(&Mail{To: "brad@drone.io", Subject: "[SUCCESS] drone", Raw: []byte("Subject: [SUCCESS] drone"), NextAttempt: now.Add(-time.Minute)})
+	database.SaveMail(&Mail{To: "burke@drone.io", Subject: "[FAILURE] drone", Raw: []byte("Subject: [FAILURE] drone"), NextAttempt: now.Add(-time.Hour)})
+	database.SaveMail(&Mail{To: "carlos@drone.io", Subject: "[FAILURE] drone", Attempts: 1, NextAttempt: now.Add(time.Hour)})
+
+	// get the mails that are due
+	mails, err := database.ListMailDue(now, 10)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// verify mails count
+	if len(mails) != 2 {
+		t.Fatalf("Exepected %d mails in list, got %d", 2, len(mails))
+	}
+
+	// the mail that is due first is listed first
+	if mails[0].To != "burke@drone.io" {
+		t.Errorf("Exepected To %s, got %s", "burke@drone.io", mails[0].To)
+	}
+
+	if string(mails[0].Raw) != "Subject: [FAILURE] drone" {
+		t.Errorf("Exepected Raw %s, got %s", "Subject: [FAILURE] drone", mails[0].Raw)
+	}
+
+	// the list is limited
+	mails, err = database.ListMailDue(now, 1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(mails) != 1 {
+		t.Errorf("Exepected %d mails in list, got %d", 1, len(mails))
+	}
+}
+
+func TestDeleteMail(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	mail := Mail{To: "brad@drone.io", NextAttempt: time.Now().UTC()}
+	if err := database.SaveMail(&mail); err != nil {
+		t.Error(err)
+	}
+	if err := database.DeleteMail(mail.ID); err != nil {
+		t.Error(err)
+	}
+
+	mails, err := database.ListMails()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(mails) != 0 {
+		t.Errorf("Exepected %d mails in list, got %d", 0, len(mails))
+	}
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/drone/drone/pkg/database"
+)
+
+func TestGetNode(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	node, err := database.GetNode(2)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if node.ID != 2 {
+		t.Errorf("Exepected ID %d, got %d", 2, node.ID)
+	}
+
+	if node.Address != "tcp://10.0.0.3:4243" {
+		t.Errorf("Exepected Address %s, got %s", "tcp://10.0.0.3:4243", node.Address)
+	}
+
+	if node.CertPath != "/etc/drone/certs" {
+		t.Errorf("Exepected CertPath %s, got %s", "/etc/drone/certs", node.CertPath)
+	}
+
+	if node.Concurrency != 4 {
+		t.Errorf("Exepected Concurrency %d, got %d", 4, node.Concurrency)
+	}
+
+	if len(node.Labels) != 1 || node.Labels[0] != "privileged" {
+		t.Errorf("Exepected Labels %v, got %v", []string{"privileged"}, node.Labels)
+	}
+}
+
+func TestSaveNode(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	// get the node we plan to update
+	node, err := database.GetNode(1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// update fields
+	node.Concurrency = 8
+
+	// update the database
+	if err := database.SaveNode(node); err != nil {
+		t.Error(err)
+	}
+
+	// get the updated node
+	updatedNode, err := database.GetNode(1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if updatedNode.Concurrency != node.Concurrency {
+		t.Errorf("Exepected Concurrency %d, got %d", node.Concurrency, updatedNode.Concurrency)
+	}
+}
+
+func TestDeleteNode(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	if err := database.DeleteNode(1); err != nil {
+		t.Error(err)
+	}
+
+	// try to get the deleted row
+	_, err := database.GetNode(1)
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestListNodes(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	nodes, err := database.ListNodes()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(nodes) != 2 {
+		t.Errorf("Exepected %d nodes in database, got %d", 2, len(nodes))
+	}
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/drone/drone/pkg/database"
+)
+
+func TestGetNotification(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	notification, err := database.GetNotification(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if notification.BuildID != 1 {
+		t.Errorf("Exepected BuildID %d, got %d", 1, notification.BuildID)
+	}
+
+	if notification.Notifier != "hipchat" {
+		t.Errorf("Exepected Notifier %s, got %s", "hipchat", notification.Notifier)
+	}
+
+	if notification.Target != "Drone Builds" {
+		t.Errorf("Exepected Target %s, got %s", "Drone Builds", notification.Target)
+	}
+
+	if notification.IsSuccess() {
+		t.Errorf("Exepected the notification to have failed")
+	}
+
+	if notification.Error != "HipChat responded with 401 Unauthorized" {
+		t.Errorf("Exepected Error %s, got %s", "HipChat responded with 401 Unauthorized", notification.Error)
+	}
+}
+
+func TestSaveNotification(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	notification, err := database.GetNotification(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a retried notification is updated
+	notification.Status = "Success"
+	notification.Error = ""
+	notification.Attempts++
+	if err := database.SaveNotification(notification); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := database.GetNotification(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !updated.IsSuccess() {
+		t.Errorf("Exepected the notification to have succeeded")
+	}
+
+	if updated.Attempts != 2 {
+		t.Errorf("Exepected Attempts %d, got %d", 2, updated.Attempts)
+	}
+
+	if updated.Updated.Before(updated.Created) {
+		t.Errorf("Exepected the Updated date to be after the Created date")
+	}
+}
+
+func TestListNotifications(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	// get the notifications for the build
+	notifications, err := database.ListNotifications(1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// verify notifications count
+	if len(notifications) != 2 {
+		t.Fatalf("Exepected %d notifications in list, got %d", 2, len(notifications))
+	}
+
+	// notifications are listed in the order they were sent
+	if notifications[0].Notifier != "email" {
+		t.Errorf("Exepected Notifier %s, got %s", "email", notifications[0].Notifier)
+	}
+
+	if notifications[1].Notifier != "hipchat" {
+		t.Errorf("Exepected Notifier %s, got %s", "hipchat", notifications[1].Notifier)
+	}
+}
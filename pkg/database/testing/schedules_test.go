@@ -0,0 +1,108 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/drone/drone/pkg/database"
+)
+
+func TestGetSchedule(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	schedule, err := database.GetSchedule(1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if schedule.ID != 1 {
+		t.Errorf("Exepected ID %d, got %d", 1, schedule.ID)
+	}
+
+	if schedule.Branch != "master" {
+		t.Errorf("Exepected Branch %s, got %s", "master", schedule.Branch)
+	}
+
+	if schedule.Spec != "0 0 * * *" {
+		t.Errorf("Exepected Spec %s, got %s", "0 0 * * *", schedule.Spec)
+	}
+}
+
+func TestSaveSchedule(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	// get the schedule we plan to update
+	schedule, err := database.GetSchedule(1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// update fields
+	schedule.Spec = "@hourly"
+
+	// update the database
+	if err := database.SaveSchedule(schedule); err != nil {
+		t.Error(err)
+	}
+
+	// get the updated schedule
+	updatedSchedule, err := database.GetSchedule(1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if updatedSchedule.Spec != schedule.Spec {
+		t.Errorf("Exepected Spec %s, got %s", schedule.Spec, updatedSchedule.Spec)
+	}
+}
+
+func TestDeleteSchedule(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	if err := database.DeleteSchedule(1); err != nil {
+		t.Error(err)
+	}
+
+	// try to get the deleted row
+	_, err := database.GetSchedule(1)
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestListSchedules(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	// repository 1 should have 2 schedules
+	schedules, err := database.ListSchedules(1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(schedules) != 2 {
+		t.Errorf("Exepected %d schedules in database, got %d", 2, len(schedules))
+		return
+	}
+
+	// results are ordered by branch
+	if schedules[0].Branch != "dev" {
+		t.Errorf("Exepected Branch %s, got %s", "dev", schedules[0].Branch)
+	}
+}
+
+func TestListAllSchedules(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	schedules, err := database.ListAllSchedules()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(schedules) != 3 {
+		t.Errorf("Exepected %d schedules in database, got %d", 3, len(schedules))
+	}
+}
@@ -29,6 +29,10 @@ func TestGetSettings(t *testing.T) {
 	settings.SmtpServer = "0.0.0.0"
 	settings.SmtpUsername = "username"
 	settings.SmtpPassword = "password"
+	settings.SmtpSecurity = "starttls"
+	settings.SmtpAuth = "cram-md5"
+	settings.BuildMemory = 512
+	settings.BuildSwap = -1
 
 	// save the updated settings
 	if err := database.SaveSettings(settings); err != nil {
@@ -53,6 +57,22 @@ func TestGetSettings(t *testing.T) {
 		t.Errorf("Exepected Domain %s, got %s", "foo.com", settings.Domain)
 	}
 
+	if settings.SmtpSecurity != "starttls" {
+		t.Errorf("Exepected SmtpSecurity %s, got %s", "starttls", settings.SmtpSecurity)
+	}
+
+	if settings.SmtpAuth != "cram-md5" {
+		t.Errorf("Exepected SmtpAuth %s, got %s", "cram-md5", settings.SmtpAuth)
+	}
+
+	if settings.BuildMemory != 512 {
+		t.Errorf("Exepected BuildMemory %d, got %d", 512, settings.BuildMemory)
+	}
+
+	if settings.BuildSwap != -1 {
+		t.Errorf("Exepected BuildSwap %d, got %d", -1, settings.BuildSwap)
+	}
+
 	// Verify caching works and is threadsafe
 	settingsA, _ := database.GetSettings()
 	settingsB, _ := database.GetSettings()
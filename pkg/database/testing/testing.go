@@ -52,7 +52,9 @@ func Setup() {
 		Email:    "brad.rydzewski@gmail.com",
 		Gravatar: "8c58a0be77ee441bb8f8595b7f1b4e87",
 		Token:    "123",
-		Admin:    true}
+		Admin:    true,
+
+		GithubLogin: "bradrydzewski"}
 	user2 := User{
 		Password: "$2a$10$b8d63QsTL38vx7lj0HEHfOdbu1PCAg6Gfca74UavkXooIBx9YxopS",
 		Name:     "Thomas Burke",
@@ -205,6 +207,25 @@ func Setup() {
 	database.SaveBuild(&Build{CommitID: commit2.ID, Slug: "node_0.09", Status: "Failure", Duration: 65})
 	database.SaveBuild(&Build{CommitID: commit3.ID, Slug: "node_0.10", Status: "Failure", Duration: 50})
 	database.SaveBuild(&Build{CommitID: commit3.ID, Slug: "node_0.09", Status: "Failure", Duration: 55})
+
+	// create dummy schedule data
+	database.SaveSchedule(&Schedule{RepoID: repo1.ID, Branch: "master", Spec: "0 0 * * *"})
+	database.SaveSchedule(&Schedule{RepoID: repo1.ID, Branch: "dev", Spec: "*/30 * * * *"})
+	database.SaveSchedule(&Schedule{RepoID: repo2.ID, Branch: "default", Spec: "@weekly"})
+
+	// create dummy delivery data
+	database.SaveDelivery(&Delivery{RepoID: repo1.ID, CommitID: commit1.ID, URL: "http://example.com/hook", Event: "success", Attempt: 1, Status: 500})
+	database.SaveDelivery(&Delivery{RepoID: repo1.ID, CommitID: commit1.ID, URL: "http://example.com/hook", Event: "success", Attempt: 2, Status: 200})
+	database.SaveDelivery(&Delivery{RepoID: repo2.ID, CommitID: commit3.ID, URL: "http://example.com/hook", Event: "failure", Attempt: 1, Status: 200})
+
+	// create dummy notification data
+	database.SaveNotification(&Notification{RepoID: repo1.ID, BuildID: 1, Notifier: "email", Target: "brad@drone.io", Event: "Success", Status: "Success", Attempts: 1})
+	database.SaveNotification(&Notification{RepoID: repo1.ID, BuildID: 1, Notifier: "hipchat", Target: "Drone Builds", Event: "Success", Status: "Failure", Error: "HipChat responded with 401 Unauthorized", Attempts: 1})
+	database.SaveNotification(&Notification{RepoID: repo1.ID, BuildID: 2, Notifier: "irc", Target: "#drone", Event: "Success", Status: "Success", Attempts: 1})
+
+	// create dummy node data
+	database.SaveNode(&Node{Address: "tcp://10.0.0.2:4243", Concurrency: 2})
+	database.SaveNode(&Node{Address: "tcp://10.0.0.3:4243", CertPath: "/etc/drone/certs", Concurrency: 4, Labels: []string{"privileged"}})
 }
 
 func Teardown() {
@@ -81,6 +81,28 @@ func TestGetUserEmail(t *testing.T) {
 	}
 }
 
+func TestGetUserGithubLogin(t *testing.T) {
+	Setup()
+	defer Teardown()
+
+	u, err := database.GetUserGithubLogin("bradrydzewski")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if u.ID != 1 {
+		t.Errorf("Exepected ID %d, got %d", 1, u.ID)
+	}
+
+	if u.Email != "brad.rydzewski@gmail.com" {
+		t.Errorf("Exepected Email %s, got %s", "brad.rydzewski@gmail.com", u.Email)
+	}
+
+	if _, err := database.GetUserGithubLogin("octocat"); err == nil {
+		t.Errorf("Exepected an error for an unknown login")
+	}
+}
+
 // TestUpdateUser tests the ability to updatee an
 // existing User in the database.
 func TestUpdateUser(t *testing.T) {
@@ -24,6 +24,13 @@ github_login, github_token, bitbucket_login, bitbucket_token, bitbucket_secret
 FROM users WHERE email = ?
 `
 
+// SQL Queries to retrieve a user by their GitHub login
+const userFindGithubLoginStmt = `
+SELECT id, email, password, token, name, gravatar, created, updated, admin,
+github_login, github_token, bitbucket_login, bitbucket_token, bitbucket_secret
+FROM users WHERE github_login = ?
+`
+
 // SQL Queries to retrieve a list of all users
 const userStmt = `
 SELECT id, email, password, token, name, gravatar, created, updated, admin,
@@ -46,6 +53,13 @@ func GetUserEmail(email string) (*User, error) {
 	return &user, err
 }
 
+// Returns the User with the given GitHub login.
+func GetUserGithubLogin(login string) (*User, error) {
+	user := User{}
+	err := meddler.QueryRow(db, &user, userFindGithubLoginStmt, login)
+	return &user, err
+}
+
 // Returns the User Password Hash for the given
 // email address.
 func GetPassEmail(email string) ([]byte, error) {
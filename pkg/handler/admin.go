@@ -176,9 +176,23 @@ func AdminSettingsUpdate(w http.ResponseWriter, r *http.Request, u *User) error
 	settings.SmtpAddress = r.FormValue("SmtpAddress")
 	settings.SmtpUsername = r.FormValue("SmtpUsername")
 	settings.SmtpPassword = r.FormValue("SmtpPassword")
+	settings.SmtpSecurity = r.FormValue("SmtpSecurity")
+	settings.SmtpAuth = r.FormValue("SmtpAuth")
 
 	settings.OpenInvitations = (r.FormValue("OpenInvitations") == "on")
 
+	// update build resource limits
+	var err error
+	if settings.BuildMemory, err = formInt64(r, "BuildMemory"); err != nil {
+		return RenderError(w, err, http.StatusBadRequest)
+	}
+	if settings.BuildSwap, err = formInt64(r, "BuildSwap"); err != nil {
+		return RenderError(w, err, http.StatusBadRequest)
+	}
+	if settings.BuildCpuShares, err = formInt64(r, "BuildCpuShares"); err != nil {
+		return RenderError(w, err, http.StatusBadRequest)
+	}
+
 	// validate user input
 	if err := settings.Validate(); err != nil {
 		return RenderError(w, err, http.StatusBadRequest)
@@ -202,6 +216,17 @@ func AdminSettingsUpdate(w http.ResponseWriter, r *http.Request, u *User) error
 	return RenderText(w, http.StatusText(http.StatusOK), http.StatusOK)
 }
 
+// Sends a test email to the User, using the saved
+// SMTP settings, and reports whether it was delivered.
+func AdminSettingsTestEmail(w http.ResponseWriter, r *http.Request, u *User) error {
+	settings := database.SettingsMust()
+	if err := mail.SendTest(settings, u.Email); err != nil {
+		return RenderText(w, err.Error(), http.StatusBadRequest)
+	}
+
+	return RenderText(w, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
 func Install(w http.ResponseWriter, r *http.Request) error {
 	// we can only perform the inital installation if no
 	// users exist in the system
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/drone/drone/pkg/agent"
+	"github.com/drone/drone/pkg/build/script"
+	"github.com/drone/drone/pkg/queue"
+)
+
+type AgentHandler struct {
+	agents *queue.Agents
+
+	// token shared with the agents. Agents
+	// are disabled if the token is empty.
+	token string
+}
+
+func NewAgentHandler(agents *queue.Agents, token string) *AgentHandler {
+	return &AgentHandler{
+		agents: agents,
+		token:  token,
+	}
+}
+
+// Waits for a build to be assigned to the agent, and
+// returns the job in JSON format. Returns No Content if
+// no build is assigned before the poll times out.
+func (h *AgentHandler) Pull(w http.ResponseWriter, r *http.Request) error {
+	if !h.authorize(r) {
+		return RenderText(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if len(name) == 0 {
+		return RenderText(w, "Agent name must be provided", http.StatusBadRequest)
+	}
+	concurrency, err := strconv.Atoi(r.FormValue("concurrency"))
+	if err != nil || concurrency < 1 {
+		return RenderText(w, "Agent concurrency must be at least 1", http.StatusBadRequest)
+	}
+
+	// stop waiting for a build if the agent
+	// disconnects, so the build is not lost.
+	var closed <-chan bool
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		closed = notifier.CloseNotify()
+	}
+
+	labels := script.SplitLabels(r.FormValue("labels"))
+	for _, label := range labels {
+		if !script.IsLabel(label) {
+			return RenderText(w, "Invalid agent label "+label, http.StatusBadRequest)
+		}
+	}
+
+	job := h.agents.Poll(name, concurrency, labels, closed)
+	if job == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return RenderJson(w, job)
+}
+
+// Receives the acknowledgement that the agent received the build.
+func (h *AgentHandler) Ack(w http.ResponseWriter, r *http.Request) error {
+	if !h.authorize(r) {
+		return RenderText(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	}
+
+	err := h.agents.Ack(r.FormValue(":id"))
+	switch {
+	case err == agent.ErrJobNotFound:
+		return RenderError(w, err, http.StatusNotFound)
+	case err != nil:
+		return RenderError(w, err, http.StatusBadRequest)
+	}
+	return RenderText(w, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// Receives the build output streamed by the agent.
+func (h *AgentHandler) Output(w http.ResponseWriter, r *http.Request) error {
+	if !h.authorize(r) {
+		return RenderText(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	}
+
+	err := h.agents.Output(r.FormValue(":id"), r.Body)
+	switch {
+	case err == agent.ErrJobNotFound:
+		return RenderError(w, err, http.StatusNotFound)
+	case err != nil:
+		return RenderError(w, err, http.StatusBadRequest)
+	}
+	return RenderText(w, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// Receives the result of the build from the agent.
+func (h *AgentHandler) Result(w http.ResponseWriter, r *http.Request) error {
+	if !h.authorize(r) {
+		return RenderText(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	}
+
+	result := agent.Result{}
+	if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+		return RenderError(w, err, http.StatusBadRequest)
+	}
+
+	err := h.agents.Done(r.FormValue(":id"), &result)
+	switch {
+	case err == agent.ErrJobNotFound:
+		return RenderError(w, err, http.StatusNotFound)
+	case err != nil:
+		return RenderError(w, err, http.StatusBadRequest)
+	}
+	return RenderText(w, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// authorize returns true if the request contains the token
+// shared with the agents. The token is compared in constant
+// time to avoid leaking it through timing.
+func (h *AgentHandler) authorize(r *http.Request) bool {
+	token := r.Header.Get(agent.TokenHeader)
+	if len(h.token) == 0 || len(token) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
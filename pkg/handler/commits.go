@@ -2,11 +2,15 @@ package handler
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/drone/drone/pkg/channel"
 	"github.com/drone/drone/pkg/database"
 	. "github.com/drone/drone/pkg/model"
+	"github.com/drone/drone/pkg/plugin/notify"
+	"github.com/drone/drone/pkg/queue"
 )
 
 // Display a specific Commit.
@@ -28,13 +32,15 @@ func CommitShow(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) err
 	}
 
 	data := struct {
-		User   *User
-		Repo   *Repo
-		Commit *Commit
-		Build  *Build
-		Builds []*Build
-		Token  string
-	}{u, repo, commit, builds[0], builds, ""}
+		User          *User
+		Repo          *Repo
+		Commit        *Commit
+		Build         *Build
+		Builds        []*Build
+		Notifications []*Notification
+		Token         string
+		Admin         bool
+	}{u, repo, commit, builds[0], builds, nil, "", isRepoAdmin(u, repo)}
 
 	// get the specific build requested by the user. instead
 	// of a database round trip, we can just loop through the
@@ -46,6 +52,13 @@ func CommitShow(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) err
 		}
 	}
 
+	// get the results of the notifications sent
+	// for the build.
+	data.Notifications, err = database.ListNotifications(data.Build.ID)
+	if err != nil {
+		return err
+	}
+
 	// generate a token to connect with the websocket
 	// handler and stream output, if the build is running.
 	data.Token = channel.Token(fmt.Sprintf(
@@ -54,3 +67,67 @@ func CommitShow(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) err
 	// render the repository template.
 	return RenderTemplate(w, "repo_commit.html", &data)
 }
+
+// Retries a failed notification of a Build, and redirects
+// to the Build, where the result is displayed.
+func NotificationRetry(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) error {
+	hash := r.FormValue(":commit")
+	labl := r.FormValue(":label")
+	id, err := strconv.ParseInt(r.FormValue(":id"), 10, 64)
+	if err != nil {
+		return RenderNotFound(w)
+	}
+
+	commit, err := database.GetCommitHash(hash, repo.ID)
+	if err != nil {
+		return RenderNotFound(w)
+	}
+	build, err := database.GetBuildSlug(labl, commit.ID)
+	if err != nil {
+		return RenderNotFound(w)
+	}
+
+	// the notification must belong to the build
+	result, err := database.GetNotification(id)
+	if err != nil || result.BuildID != build.ID || result.RepoID != repo.ID {
+		return RenderNotFound(w)
+	}
+
+	// the .drone.yml file of a pull request is written
+	// by its author, so its notifiers are not trusted
+	// with the repository params.
+	if len(commit.PullRequest) != 0 {
+		return RenderForbidden(w)
+	}
+
+	settings := database.SettingsMust()
+	context := &notify.Context{
+		Host:             settings.URL().String(),
+		Repo:             repo,
+		Commit:           commit,
+		Build:            build,
+		SaveNotification: database.SaveNotification,
+		SaveDelivery:     database.SaveDelivery,
+		FindUser:         database.GetUserGithubLogin,
+	}
+
+	// the notifier configuration is not stored with the
+	// result, since it may include tokens and passwords,
+	// so it is read from the .drone.yml file again. The
+	// result of the attempt is recorded, and displayed
+	// with the build.
+	buildscript, err := queue.FindScript(repo, commit.Hash)
+	switch {
+	case err != nil:
+		log.Printf("error retrying %s notification %d: %s\n", result.Notifier, result.ID, err)
+	case buildscript.Notifications == nil:
+		log.Printf("error retrying %s notification %d: no notifications are configured\n", result.Notifier, result.ID)
+	default:
+		if err := buildscript.Notifications.Retry(result, context); err != nil {
+			log.Printf("error retrying %s notification %d: %s\n", result.Notifier, result.ID, err)
+		}
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/%s/commit/%s/build/%s", repo.Slug, commit.Hash, build.Slug), http.StatusSeeOther)
+	return nil
+}
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"database/sql"
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/drone/drone/pkg/build/script"
@@ -140,6 +142,15 @@ func (h *HookHandler) Hook(w http.ResponseWriter, r *http.Request) error {
 		return RenderText(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 	}
 
+	// validate the build script
+	if problems := script.Validate(raw, repo.Params); problems.HasErrors() {
+		msg := "Your .drone.yml file is invalid.\n\n" + problems.String() + "\n"
+		if err := saveFailedBuild(commit, msg); err != nil {
+			return RenderText(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return RenderText(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	}
+
 	// parse the build script
 	buildscript, err := script.ParseBuild(raw, repo.Params)
 	if err != nil {
@@ -150,6 +161,16 @@ func (h *HookHandler) Hook(w http.ResponseWriter, r *http.Request) error {
 		return RenderText(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 	}
 
+	// skip the build if none of the changed files
+	// match the paths section of the build script
+	if files := changedFiles([]byte(payload)); !buildscript.Paths.Match(files) {
+		msg := "Build skipped. None of the changed files match the paths in your .drone.yml file.\n\n" + strings.Join(files, "\n") + "\n"
+		if err := saveSkippedBuild(commit, msg); err != nil {
+			return RenderText(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return RenderText(w, http.StatusText(http.StatusOK), http.StatusOK)
+	}
+
 	// save the commit to the database
 	if err := database.SaveCommit(commit); err != nil {
 		return RenderText(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -161,6 +182,7 @@ func (h *HookHandler) Hook(w http.ResponseWriter, r *http.Request) error {
 	build.CommitID = commit.ID
 	build.Created = time.Now().UTC()
 	build.Status = "Pending"
+	build.Trigger = queue.TriggerPush
 	if err := database.SaveBuild(build); err != nil {
 		return RenderText(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 	}
@@ -256,12 +278,25 @@ func (h *HookHandler) PullRequestHook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// validate the build script
+	if problems := script.Validate(raw, repo.Params); problems.HasErrors() {
+		msg := "Your .drone.yml file is invalid.\n\n" + problems.String() + "\n"
+		if err := saveFailedBuild(commit, msg); err != nil {
+			RenderText(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		RenderText(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
 	// parse the build script
 	buildscript, err := script.ParseBuild(raw, repo.Params)
 	if err != nil {
-		// TODO if the YAML is invalid we should create a commit record
-		// with an ERROR status so that the user knows why a build wasn't
-		// triggered in the system
+		msg := "Could not parse your .drone.yml file.  It needs to be a valid drone yaml file.\n\n" + err.Error() + "\n"
+		if err := saveFailedBuild(commit, msg); err != nil {
+			RenderText(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
 		RenderText(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
@@ -278,6 +313,7 @@ func (h *HookHandler) PullRequestHook(w http.ResponseWriter, r *http.Request) {
 	build.CommitID = commit.ID
 	build.Created = time.Now().UTC()
 	build.Status = "Pending"
+	build.Trigger = queue.TriggerPullRequest
 	if err := database.SaveBuild(build); err != nil {
 		RenderText(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
@@ -294,9 +330,21 @@ func (h *HookHandler) PullRequestHook(w http.ResponseWriter, r *http.Request) {
 // Helper method for saving a failed build or commit in the case where it never starts to build.
 // This can happen if the yaml is bad or doesn't exist.
 func saveFailedBuild(commit *Commit, msg string) error {
+	// TODO: Should the status be Error instead of Failure?
+	return saveFinishedBuild(commit, StatusFailure, msg)
+}
 
-	// Set the commit to failed
-	commit.Status = "Failure"
+// Helper method for saving a skipped build or commit in the case where none of the
+// changed files match the paths in the yaml.
+func saveSkippedBuild(commit *Commit, msg string) error {
+	return saveFinishedBuild(commit, StatusSkipped, msg)
+}
+
+// Helper method for saving a build or commit that finished without being executed.
+func saveFinishedBuild(commit *Commit, status, msg string) error {
+
+	// Set the commit status
+	commit.Status = status
 	commit.Created = time.Now().UTC()
 	commit.Finished = commit.Created
 	commit.Duration = 0
@@ -310,17 +358,43 @@ func saveFailedBuild(commit *Commit, msg string) error {
 	build.CommitID = commit.ID
 	build.Created = time.Now().UTC()
 	build.Finished = build.Created
-	commit.Duration = 0
-	build.Status = "Failure"
+	build.Status = status
 	build.Stdout = msg
 	if err := database.SaveBuild(build); err != nil {
 		return err
 	}
 
-	// TODO: Should the status be Error instead of Failure?
-
 	// TODO: Do we need to update the branch table too?
 
 	return nil
 
 }
+
+// changedFiles returns the union of files added, modified and
+// removed by the commits in the GitHub push hook payload.
+func changedFiles(payload []byte) []string {
+	hook := struct {
+		Commits []struct {
+			Added    []string `json:"added"`
+			Removed  []string `json:"removed"`
+			Modified []string `json:"modified"`
+		} `json:"commits"`
+	}{}
+	if err := json.Unmarshal(payload, &hook); err != nil {
+		return nil
+	}
+
+	var files []string
+	var seen = map[string]bool{}
+	for _, commit := range hook.Commits {
+		for _, list := range [][]string{commit.Added, commit.Removed, commit.Modified} {
+			for _, file := range list {
+				if !seen[file] {
+					seen[file] = true
+					files = append(files, file)
+				}
+			}
+		}
+	}
+	return files
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/drone/drone/pkg/build"
+	"github.com/drone/drone/pkg/build/script"
+	"github.com/drone/drone/pkg/database"
+	. "github.com/drone/drone/pkg/model"
+	"github.com/drone/drone/pkg/queue"
+)
+
+type NodeHandler struct {
+	pool *queue.Pool
+}
+
+func NewNodeHandler(pool *queue.Pool) *NodeHandler {
+	return &NodeHandler{
+		pool: pool,
+	}
+}
+
+// Display a list of Docker hosts that builds are
+// scheduled onto, and their current status.
+func (h *NodeHandler) List(w http.ResponseWriter, r *http.Request, u *User) error {
+	nodes, err := database.ListNodes()
+	if err != nil {
+		return err
+	}
+
+	// the status of each node is stored in
+	// the pool, indexed by address.
+	hosts := map[string]queue.Host{}
+	var agents []queue.Host
+	for _, host := range h.pool.Hosts() {
+		hosts[host.Address] = host
+		if host.IsAgent() {
+			agents = append(agents, host)
+		}
+	}
+
+	type status struct {
+		Node *Node
+		Host queue.Host
+	}
+	var list []*status
+	for _, node := range nodes {
+		list = append(list, &status{node, hosts[node.Address]})
+	}
+
+	data := struct {
+		User   *User
+		Nodes  []*status
+		Local  queue.Host
+		Agents []queue.Host
+	}{u, list, hosts[queue.HostLocal], agents}
+
+	return RenderTemplate(w, "admin_nodes.html", &data)
+}
+
+// Registers a new Docker host.
+func (h *NodeHandler) Create(w http.ResponseWriter, r *http.Request, u *User) error {
+	node := &Node{}
+	node.Address = strings.TrimSpace(r.FormValue("address"))
+	node.CertPath = strings.TrimSpace(r.FormValue("cert_path"))
+	node.Concurrency, _ = strconv.Atoi(r.FormValue("concurrency"))
+	node.Labels = script.SplitLabels(r.FormValue("labels"))
+	if err := node.Validate(); err != nil {
+		return RenderText(w, err.Error(), http.StatusBadRequest)
+	}
+	for _, label := range node.Labels {
+		if !script.IsLabel(label) {
+			return RenderText(w, "Invalid label "+label, http.StatusBadRequest)
+		}
+	}
+
+	if err := database.SaveNode(node); err != nil {
+		return RenderText(w, err.Error(), http.StatusBadRequest)
+	}
+	if err := h.pool.Reload(); err != nil {
+		return RenderText(w, err.Error(), http.StatusInternalServerError)
+	}
+
+	return RenderText(w, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// Removes a Docker host. Builds that are already
+// running on the host are not interrupted.
+func (h *NodeHandler) Delete(w http.ResponseWriter, r *http.Request, u *User) error {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return RenderError(w, err, http.StatusBadRequest)
+	}
+
+	if err := database.DeleteNode(id); err != nil {
+		return err
+	}
+	if err := h.pool.Reload(); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, "/account/admin/nodes", http.StatusSeeOther)
+	return nil
+}
+
+// Display the disk space used by containers and images
+// on each Docker host, including the containers and images
+// left behind by builds.
+func (h *NodeHandler) Usage(w http.ResponseWriter, r *http.Request, u *User) error {
+	type usage struct {
+		Host  queue.Host
+		Usage *build.Usage
+		Error string
+
+		// sizes formatted for display.
+		ContainersSize string
+		ImagesSize     string
+	}
+	var list []*usage
+	for _, host := range h.pool.DockerHosts() {
+		item := &usage{Host: host, Error: host.Error}
+		if host.Healthy {
+			var err error
+			if item.Usage, err = build.DiskUsage(host.Client); err != nil {
+				item.Error = err.Error()
+			} else {
+				item.ContainersSize = formatSize(item.Usage.ContainersSize)
+				item.ImagesSize = formatSize(item.Usage.ImagesSize)
+			}
+		}
+		list = append(list, item)
+	}
+
+	data := struct {
+		User  *User
+		Hosts []*usage
+	}{u, list}
+
+	return RenderTemplate(w, "admin_docker.html", &data)
+}
+
+// Removes the containers and images that were left
+// behind by builds from every Docker host.
+func (h *NodeHandler) Purge(w http.ResponseWriter, r *http.Request, u *User) error {
+	h.pool.Reap()
+	http.Redirect(w, r, "/account/admin/docker", http.StatusSeeOther)
+	return nil
+}
+
+// formatSize is a helper function that formats
+// a number of bytes in the largest whole unit.
+func formatSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
@@ -13,6 +13,20 @@ import (
 	"launchpad.net/goyaml"
 )
 
+// helper function that returns true if the user owns
+// the repository, or is an admin of the Team that owns
+// the repository.
+func isRepoAdmin(u *User, repo *Repo) bool {
+	if u == nil {
+		return false
+	}
+	if u.ID == repo.UserID {
+		return true
+	}
+	admin, _ := database.IsMemberAdmin(u.ID, repo.TeamID)
+	return admin
+}
+
 // Display a Repository dashboard.
 func RepoDashboard(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) error {
 	branch := r.FormValue("branch")
@@ -41,6 +55,10 @@ func RepoDashboard(w http.ResponseWriter, r *http.Request, u *User, repo *Repo)
 	// for a stream of changes for this repository
 	token := channel.Create(repo.Slug)
 
+	// only repository administrators are
+	// allowed to manually trigger builds.
+	admin := isRepoAdmin(u, repo)
+
 	data := struct {
 		User     *User
 		Repo     *Repo
@@ -48,7 +66,8 @@ func RepoDashboard(w http.ResponseWriter, r *http.Request, u *User, repo *Repo)
 		Commits  []*Commit
 		Branch   string
 		Token    string
-	}{u, repo, branches, commits, branch, token}
+		Admin    bool
+	}{u, repo, branches, commits, branch, token, admin}
 
 	return RenderTemplate(w, "repo_dashboard.html", &data)
 }
@@ -220,6 +239,21 @@ func RepoKeys(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) error
 	return RenderTemplate(w, "repo_keys.html", &data)
 }
 
+// Display the most recent webhook deliveries for the repository.
+func RepoDeliveries(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) error {
+	deliveries, err := database.ListDeliveries(repo.ID)
+	if err != nil {
+		return err
+	}
+
+	data := struct {
+		Repo       *Repo
+		User       *User
+		Deliveries []*Delivery
+	}{repo, u, deliveries}
+	return RenderTemplate(w, "repo_deliveries.html", &data)
+}
+
 // Updates an existing repository.
 func RepoUpdate(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) error {
 	switch r.FormValue("action") {
@@ -231,6 +265,25 @@ func RepoUpdate(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) err
 	default:
 		repo.Disabled = len(r.FormValue("Disabled")) == 0
 		repo.DisabledPullRequest = len(r.FormValue("DisabledPullRequest")) == 0
+		repo.Downstream = r.FormValue("Downstream")
+
+		// resource limits are a system policy, and may
+		// only be changed by system administrators.
+		if u.Admin {
+			var err error
+			if repo.Memory, err = formInt64(r, "Memory"); err != nil {
+				return RenderError(w, err, http.StatusBadRequest)
+			}
+			if repo.Swap, err = formInt64(r, "Swap"); err != nil {
+				return RenderError(w, err, http.StatusBadRequest)
+			}
+			if repo.CpuShares, err = formInt64(r, "CpuShares"); err != nil {
+				return RenderError(w, err, http.StatusBadRequest)
+			}
+			if err := repo.ValidateLimits(); err != nil {
+				return RenderError(w, err, http.StatusBadRequest)
+			}
+		}
 
 		// value of "" indicates the currently authenticated user
 		// should be set as the administrator.
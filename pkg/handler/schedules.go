@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/drone/drone/pkg/database"
+	. "github.com/drone/drone/pkg/model"
+)
+
+// Display a list of scheduled builds for the repository.
+func RepoSchedules(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) error {
+	schedules, err := database.ListSchedules(repo.ID)
+	if err != nil {
+		return err
+	}
+
+	data := struct {
+		Repo      *Repo
+		User      *User
+		Schedules []*Schedule
+	}{repo, u, schedules}
+	return RenderTemplate(w, "repo_schedules.html", &data)
+}
+
+// Creates a new scheduled build for the repository.
+func RepoScheduleCreate(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) error {
+	schedule := &Schedule{}
+	schedule.RepoID = repo.ID
+	schedule.Branch = strings.TrimSpace(r.FormValue("branch"))
+	schedule.Spec = strings.TrimSpace(r.FormValue("spec"))
+	if err := schedule.Validate(); err != nil {
+		return RenderText(w, err.Error(), http.StatusBadRequest)
+	}
+
+	if err := database.SaveSchedule(schedule); err != nil {
+		return RenderText(w, err.Error(), http.StatusBadRequest)
+	}
+
+	return RenderText(w, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// Deletes a scheduled build from the repository.
+func RepoScheduleDelete(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) error {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return RenderError(w, err, http.StatusBadRequest)
+	}
+
+	// make sure the schedule belongs to this repository
+	schedule, err := database.GetSchedule(id)
+	if err != nil || schedule.RepoID != repo.ID {
+		return RenderNotFound(w)
+	}
+
+	if err := database.DeleteSchedule(schedule.ID); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, "/"+repo.Slug+"/schedules", http.StatusSeeOther)
+	return nil
+}
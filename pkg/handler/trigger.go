@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	. "github.com/drone/drone/pkg/model"
+	"github.com/drone/drone/pkg/queue"
+	"launchpad.net/goyaml"
+)
+
+type TriggerHandler struct {
+	queue *queue.Queue
+}
+
+func NewTriggerHandler(queue *queue.Queue) *TriggerHandler {
+	return &TriggerHandler{
+		queue: queue,
+	}
+}
+
+// Manually triggers a build of a branch head, or of a
+// specific commit, with optional params that override the
+// repository params. Returns the pending Commit in JSON
+// format.
+func (h *TriggerHandler) Build(w http.ResponseWriter, r *http.Request, u *User, repo *Repo) error {
+	req := &queue.Request{
+		Branch:  strings.TrimSpace(r.FormValue("branch")),
+		Commit:  strings.TrimSpace(r.FormValue("commit")),
+		Trigger: queue.TriggerManual,
+		User:    u,
+	}
+	if len(req.Branch) == 0 {
+		req.Branch = repo.DefaultBranch()
+	}
+
+	// params are provided in YAML format, the same
+	// as the repository params.
+	if err := goyaml.Unmarshal([]byte(r.FormValue("params")), &req.Params); err != nil {
+		return RenderText(w, "Invalid params. "+err.Error(), http.StatusBadRequest)
+	}
+
+	task, err := queue.NewTask(repo, req)
+	switch {
+	case err == queue.ErrBuildInProgress:
+		return RenderText(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		return RenderText(w, err.Error(), http.StatusBadRequest)
+	}
+
+	h.queue.Add(task)
+
+	return RenderJson(w, task.Commit)
+}
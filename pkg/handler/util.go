@@ -2,7 +2,9 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -89,6 +91,20 @@ func IsHttps(r *http.Request) bool {
 	return false
 }
 
+// formInt64 parses the integer in the form field, which
+// is zero if the field is empty.
+func formInt64(r *http.Request, name string) (int64, error) {
+	value := strings.TrimSpace(r.FormValue(name))
+	if len(value) == 0 {
+		return 0, nil
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %s %q, expected a number", name, value)
+	}
+	return i, nil
+}
+
 // GetCookie retrieves and verifies the signed cookie value.
 func GetCookie(r *http.Request, name string) string {
 	cookie, err := r.Cookie(name)
@@ -0,0 +1,106 @@
+package mail
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineLength is the maximum length of a quoted-printable
+// line, and of an encoded word, excluding the line break.
+const maxLineLength = 76
+
+// writeQuoted writes the quoted-printable encoding of
+// the body, so that long lines and non-ASCII characters
+// survive the SMTP transport. Lines end with CRLF, and
+// longer lines are wrapped with soft line breaks.
+func writeQuoted(w io.Writer, body string) error {
+	buf := bufio.NewWriter(w)
+	lines := strings.Split(strings.Replace(body, "\r\n", "\n", -1), "\n")
+	for i, line := range lines {
+		var n int
+		for j := 0; j < len(line); j++ {
+			enc := quoteByte(line[j], j == len(line)-1)
+
+			// a soft line break is an "=" at the end
+			// of the line, which counts towards the
+			// line length.
+			if n+len(enc) > maxLineLength-1 {
+				buf.WriteString("=\r\n")
+				n = 0
+			}
+			buf.WriteString(enc)
+			n += len(enc)
+		}
+		if i != len(lines)-1 {
+			buf.WriteString("\r\n")
+		}
+	}
+	return buf.Flush()
+}
+
+// quoteByte returns the quoted-printable encoding of the
+// byte. Whitespace is encoded at the end of a line, since
+// it may be removed in transport.
+func quoteByte(b byte, last bool) string {
+	switch {
+	case b == '=':
+	case b == ' ' || b == '\t':
+		if !last {
+			return string(b)
+		}
+	case b >= '!' && b <= '~':
+		return string(b)
+	}
+	return fmt.Sprintf("=%02X", b)
+}
+
+// encodeHeader returns the header value, encoded as RFC 2047
+// encoded words using the Q encoding if the value contains
+// non-ASCII characters. Each encoded word is no longer than
+// allowed, and multi-byte characters are not split.
+func encodeHeader(value string) string {
+	if !needsEncoding(value) {
+		return value
+	}
+
+	const prefix, suffix = "=?UTF-8?q?", "?="
+	var words []string
+	var word string
+	for _, r := range value {
+		var enc string
+		buf := make([]byte, utf8.UTFMax)
+		for _, b := range buf[:utf8.EncodeRune(buf, r)] {
+			switch {
+			case b == ' ':
+				enc += "_"
+			case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+				enc += string(b)
+			case strings.IndexByte("!*+-/", b) != -1:
+				enc += string(b)
+			default:
+				enc += fmt.Sprintf("=%02X", b)
+			}
+		}
+		if len(prefix)+len(word)+len(enc)+len(suffix) > maxLineLength-1 {
+			words = append(words, prefix+word+suffix)
+			word = ""
+		}
+		word += enc
+	}
+	words = append(words, prefix+word+suffix)
+	return strings.Join(words, " ")
+}
+
+// needsEncoding returns true if the header value contains
+// characters that are not printable ASCII.
+func needsEncoding(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if b := value[i]; (b < ' ' || b > '~') && b != '\t' {
+			return true
+		}
+	}
+	return false
+}
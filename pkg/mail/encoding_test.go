@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteQuoted(t *testing.T) {
+	var tests = []struct {
+		body    string
+		encoded string
+	}{
+		{"Commit 4f4c45 Failed", "Commit 4f4c45 Failed"},
+		{"a=b\nc\r\nd", "a=3Db\r\nc\r\nd"},
+		{"Café", "Caf=C3=A9"},
+		{"trailing \nspace\t", "trailing=20\r\nspace=09"},
+		{strings.Repeat("a", 80), strings.Repeat("a", 75) + "=\r\naaaaa"},
+		{strings.Repeat("a", 74) + "é", strings.Repeat("a", 74) + "=\r\n=C3=A9"},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := writeQuoted(&buf, test.body); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.encoded {
+			t.Errorf("Expected %q encoded as %q, got %q", test.body, test.encoded, buf.String())
+		}
+	}
+}
+
+func TestEncodeHeader(t *testing.T) {
+	var tests = []struct {
+		value   string
+		encoded string
+	}{
+		{"[FAILURE] drone", "[FAILURE] drone"},
+		{"[SUCCESS] café", "=?UTF-8?q?=5BSUCCESS=5D_caf=C3=A9?="},
+		{strings.Repeat("é", 20), "=?UTF-8?q?" + strings.Repeat("=C3=A9", 10) + "?= =?UTF-8?q?" + strings.Repeat("=C3=A9", 10) + "?="},
+	}
+
+	for _, test := range tests {
+		if encoded := encodeHeader(test.value); encoded != test.encoded {
+			t.Errorf("Expected %q encoded as %q, got %q", test.value, test.encoded, encoded)
+		}
+	}
+}
@@ -2,14 +2,24 @@ package mail
 
 import (
 	"bytes"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"log"
-	"net/smtp"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"time"
 
 	"github.com/drone/drone/pkg/database"
+	"github.com/drone/drone/pkg/model"
 	"github.com/drone/drone/pkg/template"
 )
 
+// ErrNotConfigured indicates the SMTP server
+// is not configured in the settings.
+var ErrNotConfigured = errors.New("SMTP server is not configured")
+
 // A Message represents an email message. Addresses may be of any
 // form permitted by RFC 822.
 type Message struct {
@@ -18,7 +28,11 @@ type Message struct {
 
 	To      string
 	Subject string
-	Body    string
+
+	// Body is the HTML body of the message, and Text
+	// is the plain-text alternative, which may be empty.
+	Body string
+	Text string
 }
 
 // Sends a activation email to the User.
@@ -87,6 +101,14 @@ func SendSuccess(repo, to string, data interface{}) error {
 	}
 	msg.Body = buf.String()
 
+	buf.Reset()
+	err = template.ExecuteTemplate(&buf, "success.txt", &data)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+	msg.Text = buf.String()
+
 	return Send(&msg)
 }
 
@@ -104,10 +126,19 @@ func SendFailure(repo, to string, data interface{}) error {
 	}
 	msg.Body = buf.String()
 
+	buf.Reset()
+	err = template.ExecuteTemplate(&buf, "failure.txt", &data)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+	msg.Text = buf.String()
+
 	return Send(&msg)
 }
 
-// Send sends an email message.
+// Send adds an email message to the outbound queue,
+// to be delivered in the background.
 func Send(msg *Message) error {
 	// retieve the system settings from the database
 	// so that we can get the SMTP details.
@@ -116,33 +147,116 @@ func Send(msg *Message) error {
 		log.Print(err)
 		return err
 	}
+	if len(s.SmtpServer) == 0 {
+		return ErrNotConfigured
+	}
 
 	// set the FROM address
 	msg.Sender = s.SmtpAddress
 
-	// format the raw email message body
-	body := fmt.Sprintf(emailTemplate, msg.Sender, msg.To, msg.Subject, msg.Body)
+	// format the raw email message
+	raw, err := format(msg, time.Now())
+	if err != nil {
+		log.Print(err)
+		return err
+	}
 
-	var auth smtp.Auth
-	if len(s.SmtpUsername) > 0 {
-		auth = smtp.PlainAuth("", s.SmtpUsername, s.SmtpPassword, s.SmtpServer)
+	if err := enqueue(msg, raw); err != nil {
+		log.Print(err)
+		return err
 	}
-	addr := fmt.Sprintf("%s:%s", s.SmtpServer, s.SmtpPort)
+	return nil
+}
 
-	err = smtp.SendMail(addr, auth, msg.Sender, []string{msg.To}, []byte(body))
+// SendTest sends a test email message immediately,
+// bypassing the queue, so that the SMTP settings
+// can be verified.
+func SendTest(s *model.Settings, to string) error {
+	if len(s.SmtpServer) == 0 {
+		return ErrNotConfigured
+	}
+
+	msg := Message{}
+	msg.Sender = s.SmtpAddress
+	msg.Subject = "[drone.io] Test Email"
+	msg.To = to
+	msg.Body = "<p>The SMTP settings of your Drone server are working.</p>"
+	msg.Text = "The SMTP settings of your Drone server are working."
+
+	raw, err := format(&msg, time.Now())
 	if err != nil {
-		log.Print(err)
 		return err
 	}
+	return deliver(s, msg.Sender, msg.To, raw)
+}
 
-	return nil
+// format returns the raw email message, including the
+// Date and Message-ID headers. The message is multipart,
+// with a plain-text alternative, if the Text is not empty.
+func format(msg *Message, date time.Time) ([]byte, error) {
+	id, err := messageID(msg.Sender, date)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", msg.Sender)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	if len(msg.ReplyTo) != 0 {
+		fmt.Fprintf(&buf, "Reply-To: %s\r\n", msg.ReplyTo)
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", encodeHeader(msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: %s\r\n", id)
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+
+	// messages without a plain-text alternative
+	// are sent as a single html part.
+	if len(msg.Text) == 0 {
+		fmt.Fprintf(&buf, "Content-Type: text/html; charset=\"UTF-8\"\r\n")
+		fmt.Fprintf(&buf, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuoted(&buf, msg.Body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	// the parts are ordered by preference, so
+	// the html part comes last.
+	writer := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.Body},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType+"; charset=\"UTF-8\"")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuoted(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
-// Text-template used to generate a raw Email message
-var emailTemplate = `From: %s
-To: %s
-Subject: %s
-MIME-version: 1.0
-Content-Type: text/html; charset="UTF-8"
+// messageID returns a unique Message-ID header value,
+// using the domain of the sender address.
+func messageID(sender string, date time.Time) (string, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
 
-%s`
+	domain := "drone"
+	if i := strings.LastIndex(sender, "@"); i != -1 {
+		domain = strings.Trim(sender[i+1:], "> ")
+	}
+	return fmt.Sprintf("<%d.%x@%s>", date.UnixNano(), random, domain), nil
+}
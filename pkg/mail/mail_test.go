@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	msg := &Message{
+		Sender:  "drone@example.com",
+		To:      "brad@drone.io",
+		Subject: "[FAILURE] drone",
+		Body:    "<p>Commit 4f4c45 Failed</p>",
+		Text:    "Commit 4f4c45 Failed",
+	}
+	date := time.Date(2014, time.March, 21, 12, 0, 0, 0, time.UTC)
+
+	raw, err := format(msg, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := parsed.Header.Get("Subject"); got != "[FAILURE] drone" {
+		t.Errorf("Expected subject [FAILURE] drone, got %s", got)
+	}
+	if got, _ := parsed.Header.Date(); !got.Equal(date) {
+		t.Errorf("Expected date %s, got %s", date, got)
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Expected a Message-ID at the sender domain, got %s", id)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Expected a multipart/alternative message, got %s", mediaType)
+	}
+
+	// the plain-text part comes first, followed
+	// by the preferred html part.
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.Body},
+	} {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := part.Header.Get("Content-Type"); !strings.HasPrefix(got, want.contentType) {
+			t.Errorf("Expected content type %s, got %s", want.contentType, got)
+		}
+		// the multipart reader decodes quoted-printable
+		// parts transparently.
+		body, _ := ioutil.ReadAll(part)
+		if string(body) != want.body {
+			t.Errorf("Expected body %q, got %q", want.body, body)
+		}
+	}
+}
+
+func TestFormatHTML(t *testing.T) {
+	msg := &Message{
+		Sender:  "drone@example.com",
+		To:      "brad@drone.io",
+		Subject: "Café",
+		Body:    "<p>" + strings.Repeat("a", 100) + "</p>",
+	}
+
+	raw, err := format(msg, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// messages without a plain-text alternative
+	// are a single html part.
+	if got := parsed.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Errorf("Expected a text/html message, got %s", got)
+	}
+	if subject := parsed.Header.Get("Subject"); subject != "=?UTF-8?q?Caf=C3=A9?=" {
+		t.Errorf("Expected the subject to be encoded, got %s", subject)
+	}
+	body, _ := ioutil.ReadAll(parsed.Body)
+	if want := msg.Body[:75] + "=\r\n" + msg.Body[75:]; string(body) != want {
+		t.Errorf("Expected body %q, got %q", want, body)
+	}
+}
@@ -0,0 +1,112 @@
+package mail
+
+import (
+	"log"
+	"time"
+
+	"github.com/drone/drone/pkg/database"
+	"github.com/drone/drone/pkg/model"
+)
+
+// maxAttempts is the number of times delivery of
+// a message is attempted before it is dropped.
+const maxAttempts = 6
+
+// queueBatch is the maximum number of messages
+// delivered each time the queue is flushed.
+const queueBatch = 50
+
+// wakeup signals the queue that a message was
+// added, and should be delivered immediately.
+var wakeup = make(chan bool, 1)
+
+// enqueue adds the raw message to the outbound
+// queue, to be delivered in the background.
+func enqueue(msg *Message, raw []byte) error {
+	mail := &model.Mail{
+		Sender:      msg.Sender,
+		To:          msg.To,
+		Subject:     msg.Subject,
+		Raw:         raw,
+		NextAttempt: time.Now().UTC(),
+	}
+	if err := database.SaveMail(mail); err != nil {
+		return err
+	}
+
+	// wake up the queue, unless it is already awake
+	select {
+	case wakeup <- true:
+	default:
+	}
+	return nil
+}
+
+// StartQueue starts delivering queued messages in the
+// background, when they are added to the queue, and
+// periodically to retry failed deliveries.
+func StartQueue(interval time.Duration) {
+	go func() {
+		for {
+			if n := flush(time.Now()); n == queueBatch {
+				// there may be more messages due
+				continue
+			}
+
+			select {
+			case <-wakeup:
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// flush delivers the messages that are due, and
+// schedules a retry of failed deliveries. It returns
+// the number of messages it attempted to deliver.
+func flush(now time.Time) int {
+	mails, err := database.ListMailDue(now, queueBatch)
+	if err != nil {
+		log.Printf("failed to list queued mail. %s", err)
+		return 0
+	}
+	if len(mails) == 0 {
+		return 0
+	}
+
+	// the settings are retrieved for each batch,
+	// so that changes apply to queued messages.
+	settings, err := database.GetSettings()
+	if err != nil {
+		log.Printf("failed to get SMTP settings. %s", err)
+		return 0
+	}
+
+	for _, mail := range mails {
+		err := deliver(settings, mail.Sender, mail.To, mail.Raw)
+		if err == nil {
+			database.DeleteMail(mail.ID)
+			continue
+		}
+
+		mail.Attempts++
+		mail.Error = err.Error()
+		if mail.Attempts >= maxAttempts {
+			log.Printf("failed to send email %q to %s after %d attempts. %s", mail.Subject, mail.To, mail.Attempts, err)
+			database.DeleteMail(mail.ID)
+			continue
+		}
+
+		log.Printf("failed to send email %q to %s, will retry. %s", mail.Subject, mail.To, err)
+		mail.NextAttempt = now.Add(retryDelay(mail.Attempts)).UTC()
+		database.SaveMail(mail)
+	}
+	return len(mails)
+}
+
+// retryDelay returns the delay before the next attempt to
+// deliver a message, which doubles after each attempt,
+// starting at one minute.
+func retryDelay(attempts int) time.Duration {
+	return time.Minute << uint(attempts-1)
+}
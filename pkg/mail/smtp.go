@@ -0,0 +1,116 @@
+package mail
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/smtp"
+	"time"
+
+	"github.com/drone/drone/pkg/model"
+)
+
+// ErrStartTLS indicates the SMTP server does not support
+// STARTTLS, which is required by the settings.
+var ErrStartTLS = errors.New("SMTP server does not support STARTTLS")
+
+// dialTimeout is the maximum amount of time to wait for
+// a connection, and sendTimeout the maximum amount of time
+// to deliver a message, so that an unresponsive server
+// cannot stall the queue.
+var (
+	dialTimeout = 30 * time.Second
+	sendTimeout = 2 * time.Minute
+)
+
+// tlsConfig returns the TLS configuration used to
+// verify the SMTP server.
+var tlsConfig = func(host string) *tls.Config {
+	return &tls.Config{ServerName: host}
+}
+
+// deliver sends the raw message to the recipient using
+// the SMTP server, security and authentication mechanism
+// in the settings.
+func deliver(s *model.Settings, from, to string, raw []byte) error {
+	addr := net.JoinHostPort(s.SmtpServer, s.SmtpPort)
+
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(sendTimeout))
+
+	// the connection is encrypted before the SMTP
+	// handshake when implicit TLS is required.
+	if s.SmtpSecurity == model.SmtpSecurityTLS {
+		tlsConn := tls.Client(conn, tlsConfig(s.SmtpServer))
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return err
+		}
+		conn = tlsConn
+	}
+
+	client, err := smtp.NewClient(conn, s.SmtpServer)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	// upgrade the connection if the server supports
+	// it, unless it is already encrypted.
+	if s.SmtpSecurity != model.SmtpSecurityTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig(s.SmtpServer)); err != nil {
+				return err
+			}
+		} else if s.SmtpSecurity == model.SmtpSecurityStartTLS {
+			return ErrStartTLS
+		}
+	}
+
+	if auth := smtpAuth(s); auth != nil {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// smtpAuth returns the authentication mechanism in the
+// settings, or nil if authentication is disabled.
+func smtpAuth(s *model.Settings) smtp.Auth {
+	if len(s.SmtpUsername) == 0 {
+		return nil
+	}
+
+	switch s.SmtpAuth {
+	case model.SmtpAuthNone:
+		return nil
+	case model.SmtpAuthCramMD5:
+		return smtp.CRAMMD5Auth(s.SmtpUsername, s.SmtpPassword)
+	default:
+		return smtp.PlainAuth("", s.SmtpUsername, s.SmtpPassword, s.SmtpServer)
+	}
+}
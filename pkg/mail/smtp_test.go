@@ -0,0 +1,295 @@
+package mail
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/pkg/model"
+)
+
+// testServer is a minimal, in-process SMTP server
+// that records the commands and messages it receives.
+type testServer struct {
+	listener net.Listener
+	starttls bool
+	config   *tls.Config
+
+	sync.Mutex
+	commands []string
+	messages []string
+}
+
+// newTestServer starts an SMTP server on the loopback
+// interface. The server accepts implicit TLS if implicit
+// is true, and advertises STARTTLS if starttls is true.
+func newTestServer(t *testing.T, implicit, starttls bool) *testServer {
+	// borrow the certificate of the httptest package,
+	// which is valid for 127.0.0.1.
+	cert := httptest.NewUnstartedServer(nil)
+	cert.StartTLS()
+	cert.Close()
+
+	ca, err := x509.ParseCertificate(cert.TLS.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	config := &tls.Config{Certificates: cert.TLS.Certificates}
+	tlsConfig = func(host string) *tls.Config {
+		return &tls.Config{ServerName: host, RootCAs: pool}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if implicit {
+		listener = tls.NewListener(listener, config)
+	}
+
+	server := &testServer{listener: listener, starttls: starttls, config: config}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+// settings returns the SMTP settings to connect
+// to the server.
+func (s *testServer) settings() *model.Settings {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return &model.Settings{SmtpServer: host, SmtpPort: port, SmtpAddress: "drone@example.com"}
+}
+
+func (s *testServer) Close() {
+	s.listener.Close()
+	tlsConfig = func(host string) *tls.Config {
+		return &tls.Config{ServerName: host}
+	}
+}
+
+func (s *testServer) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost ESMTP")
+
+	encrypted := !s.starttls
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		s.Lock()
+		s.commands = append(s.commands, line)
+		s.Unlock()
+
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO":
+			text.PrintfLine("250-localhost")
+			if !encrypted {
+				text.PrintfLine("250-STARTTLS")
+			}
+			text.PrintfLine("250 AUTH PLAIN CRAM-MD5")
+		case "STARTTLS":
+			text.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, s.config)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, encrypted = tlsConn, true
+			text = textproto.NewConn(conn)
+		case "AUTH":
+			text.PrintfLine("235 authenticated")
+		case "DATA":
+			text.PrintfLine("354 go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.Lock()
+			s.messages = append(s.messages, string(data))
+			s.Unlock()
+			text.PrintfLine("250 ok")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("250 ok")
+		}
+	}
+}
+
+// received returns the commands and messages
+// received by the server.
+func (s *testServer) received() ([]string, []string) {
+	s.Lock()
+	defer s.Unlock()
+	return s.commands, s.messages
+}
+
+func TestDeliver(t *testing.T) {
+	server := newTestServer(t, false, false)
+	defer server.Close()
+
+	raw := []byte("Subject: [SUCCESS] drone\r\n\r\nCommit 4f4c45 Passed\r\n")
+	if err := deliver(server.settings(), "drone@example.com", "brad@drone.io", raw); err != nil {
+		t.Fatal(err)
+	}
+
+	commands, messages := server.received()
+	want := []string{"MAIL FROM:<drone@example.com>", "RCPT TO:<brad@drone.io>"}
+	for _, command := range want {
+		if !contains(commands, command) {
+			t.Errorf("Expected command %s, got %v", command, commands)
+		}
+	}
+	// the server reads the message with normalized
+	// line endings.
+	if len(messages) != 1 || messages[0] != strings.Replace(string(raw), "\r\n", "\n", -1) {
+		t.Errorf("Expected the raw message to be delivered, got %q", messages)
+	}
+}
+
+func TestDeliverAuth(t *testing.T) {
+	server := newTestServer(t, false, false)
+	defer server.Close()
+
+	// authentication is skipped without a username
+	settings := server.settings()
+	deliver(settings, "drone@example.com", "brad@drone.io", nil)
+	if commands, _ := server.received(); hasPrefix(commands, "AUTH") {
+		t.Errorf("Expected no authentication without a username, got %v", commands)
+	}
+
+	// the plain mechanism is used by default
+	settings.SmtpUsername = "drone"
+	settings.SmtpPassword = "password"
+	if err := deliver(settings, "drone@example.com", "brad@drone.io", nil); err != nil {
+		t.Fatal(err)
+	}
+	if commands, _ := server.received(); !contains(commands, "AUTH PLAIN AGRyb25lAHBhc3N3b3Jk") {
+		t.Errorf("Expected plain authentication, got %v", commands)
+	}
+
+	// authentication is disabled
+	server.Lock()
+	server.commands = nil
+	server.Unlock()
+	settings.SmtpAuth = model.SmtpAuthNone
+	if err := deliver(settings, "drone@example.com", "brad@drone.io", nil); err != nil {
+		t.Fatal(err)
+	}
+	if commands, _ := server.received(); hasPrefix(commands, "AUTH") {
+		t.Errorf("Expected no authentication, got %v", commands)
+	}
+}
+
+func TestDeliverStartTLS(t *testing.T) {
+	// the server does not support STARTTLS
+	server := newTestServer(t, false, false)
+	settings := server.settings()
+	settings.SmtpSecurity = model.SmtpSecurityStartTLS
+	if err := deliver(settings, "drone@example.com", "brad@drone.io", nil); err != ErrStartTLS {
+		t.Errorf("Expected ErrStartTLS, got %v", err)
+	}
+	server.Close()
+
+	// the connection is upgraded when supported
+	server = newTestServer(t, false, true)
+	defer server.Close()
+	settings = server.settings()
+	settings.SmtpSecurity = model.SmtpSecurityStartTLS
+	if err := deliver(settings, "drone@example.com", "brad@drone.io", []byte("Subject: test\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if commands, messages := server.received(); !contains(commands, "STARTTLS") || len(messages) != 1 {
+		t.Errorf("Expected the message to be delivered after STARTTLS, got %v", commands)
+	}
+}
+
+func TestDeliverTLS(t *testing.T) {
+	server := newTestServer(t, true, false)
+	defer server.Close()
+
+	settings := server.settings()
+	settings.SmtpSecurity = model.SmtpSecurityTLS
+	if err := deliver(settings, "drone@example.com", "brad@drone.io", []byte("Subject: test\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, messages := server.received(); len(messages) != 1 {
+		t.Errorf("Expected the message to be delivered over TLS, got %d messages", len(messages))
+	}
+
+	// a plain connection to a TLS server fails
+	dialTimeout, sendTimeout = time.Second, time.Second
+	defer func() { dialTimeout, sendTimeout = 30*time.Second, 2*time.Minute }()
+	settings.SmtpSecurity = model.SmtpSecurityNone
+	if err := deliver(settings, "drone@example.com", "brad@drone.io", nil); err == nil {
+		t.Errorf("Expected an error connecting to a TLS server without TLS")
+	}
+}
+
+func TestSendTest(t *testing.T) {
+	server := newTestServer(t, false, false)
+	defer server.Close()
+
+	if err := SendTest(server.settings(), "brad@drone.io"); err != nil {
+		t.Fatal(err)
+	}
+	if _, messages := server.received(); len(messages) != 1 || !strings.Contains(messages[0], "Subject: [drone.io] Test Email") {
+		t.Errorf("Expected a test email, got %q", messages)
+	}
+
+	if err := SendTest(&model.Settings{}, "brad@drone.io"); err != ErrNotConfigured {
+		t.Errorf("Expected ErrNotConfigured, got %v", err)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	var tests = []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{5, 16 * time.Minute},
+	}
+	for _, test := range tests {
+		if got := retryDelay(test.attempts); got != test.delay {
+			t.Errorf("Expected delay %s after %d attempts, got %s", test.delay, test.attempts, got)
+		}
+	}
+}
+
+func contains(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(lines []string, prefix string) bool {
+	for _, l := range lines {
+		if strings.HasPrefix(l, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -12,6 +12,7 @@ const (
 	StatusSuccess = "Success"
 	StatusFailure = "Failure"
 	StatusError   = "Error"
+	StatusSkipped = "Skipped"
 )
 
 type Build struct {
@@ -25,6 +26,29 @@ type Build struct {
 	Created  time.Time `meddler:"created,utctime"  json:"created"`
 	Updated  time.Time `meddler:"updated,utctime"  json:"updated"`
 	Stdout   string    `meddler:"stdout"           json:"-"`
+
+	// Trigger is the event that caused the build, such
+	// as push, pull_request, cron or manual, and is
+	// exposed to the build as DRONE_TRIGGER.
+	Trigger string `meddler:"trigger_type" json:"trigger"`
+
+	// TriggeredBy is the name of the user that
+	// manually triggered the build, if any.
+	TriggeredBy string `meddler:"triggered_by" json:"triggered_by"`
+
+	// Upstream is the path of the upstream commit whose
+	// successful build triggered this build, for example
+	// github.com/drone/drone/commit/4f4c4594be6d.
+	Upstream string `meddler:"upstream" json:"upstream"`
+
+	// Node is the address of the Docker host
+	// that ran the build.
+	Node string `meddler:"node" json:"node"`
+
+	// Waiting is the reason a pending build is waiting
+	// for a host, for example because no host has the
+	// labels required by the build.
+	Waiting string `meddler:"waiting" json:"waiting"`
 }
 
 // HumanDuration returns a human-readable approximation of a duration
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+)
+
+// Delivery represents a single attempt to deliver
+// a webhook notification for a build.
+type Delivery struct {
+	ID       int64 `meddler:"id,pk"     json:"id"`
+	RepoID   int64 `meddler:"repo_id"   json:"-"`
+	CommitID int64 `meddler:"commit_id" json:"commit_id"`
+
+	// URL the notification was posted to.
+	URL string `meddler:"url" json:"url"`
+
+	// Event is the build event that triggered the
+	// notification, such as started or failure.
+	Event string `meddler:"event" json:"event"`
+
+	// Attempt is the number of the attempt, starting
+	// at 1, since failed deliveries are retried.
+	Attempt int `meddler:"attempt" json:"attempt"`
+
+	// Status is the HTTP status code of the response,
+	// or 0 if no response was received.
+	Status int `meddler:"status" json:"status"`
+
+	// Error describes why the delivery failed.
+	Error string `meddler:"error" json:"error"`
+
+	// Duration of the request, in milliseconds.
+	Duration int64 `meddler:"duration" json:"duration"`
+
+	Created time.Time `meddler:"created,utctime" json:"created"`
+}
+
+// IsSuccess returns true if the webhook responded
+// with a 2xx status code.
+func (d *Delivery) IsSuccess() bool {
+	return d.Status >= 200 && d.Status < 300
+}
+
+// Returns the Created Date as an ISO8601
+// formatted string.
+func (d *Delivery) CreatedString() string {
+	return d.Created.Format("2006-01-02T15:04:05Z")
+}
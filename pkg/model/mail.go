@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+)
+
+// Mail represents an outbound email message
+// waiting in the queue to be delivered.
+type Mail struct {
+	ID      int64  `meddler:"id,pk"   json:"id"`
+	Sender  string `meddler:"sender"  json:"sender"`
+	To      string `meddler:"to_addr" json:"to"`
+	Subject string `meddler:"subject" json:"subject"`
+
+	// Raw is the formatted message, including
+	// the headers.
+	Raw []byte `meddler:"raw" json:"-"`
+
+	// Attempts is the number of failed attempts
+	// to deliver the message, and Error is the
+	// reason the last attempt failed.
+	Attempts int    `meddler:"attempts" json:"attempts"`
+	Error    string `meddler:"error"    json:"error"`
+
+	// NextAttempt is the earliest time the
+	// message is delivered.
+	NextAttempt time.Time `meddler:"next_attempt,utctime" json:"next_attempt"`
+
+	Created time.Time `meddler:"created,utctime" json:"created"`
+	Updated time.Time `meddler:"updated,utctime" json:"updated"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidNodeAddress     = errors.New("Invalid Node Address, expected tcp://host:port or unix:///path/to/docker.sock")
+	ErrInvalidNodeConcurrency = errors.New("Node Concurrency must be at least 1")
+)
+
+// Node represents a Docker host that
+// builds are scheduled onto.
+type Node struct {
+	ID int64 `meddler:"id,pk" json:"id"`
+
+	// Address of the Docker daemon, for example
+	// tcp://10.0.0.2:4243 or unix:///var/run/docker.sock.
+	Address string `meddler:"address" json:"address"`
+
+	// CertPath is the directory on the Drone server that
+	// contains the ca.pem, cert.pem and key.pem files used
+	// to connect to the Docker daemon over TLS, if any.
+	CertPath string `meddler:"cert_path" json:"cert_path"`
+
+	// Concurrency is the maximum number of builds
+	// that run on the Docker host at the same time.
+	Concurrency int `meddler:"concurrency" json:"concurrency"`
+
+	// Labels advertised by the Docker host, such as
+	// privileged or highmem, which are matched against
+	// the labels required by each build.
+	Labels []string `meddler:"labels,json" json:"labels"`
+
+	Created time.Time `meddler:"created,utctime" json:"created"`
+	Updated time.Time `meddler:"updated,utctime" json:"updated"`
+}
+
+// Validate verifies all required fields are correctly populated.
+func (n *Node) Validate() error {
+	switch {
+	case !strings.HasPrefix(n.Address, "tcp://") && !strings.HasPrefix(n.Address, "unix://"):
+		return ErrInvalidNodeAddress
+	case n.Address == "tcp://" || n.Address == "unix://":
+		return ErrInvalidNodeAddress
+	case n.Concurrency < 1:
+		return ErrInvalidNodeConcurrency
+	default:
+		return nil
+	}
+}
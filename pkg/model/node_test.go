@@ -0,0 +1,27 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestNodeValidate(t *testing.T) {
+	var tests = []struct {
+		address     string
+		concurrency int
+		err         error
+	}{
+		{"tcp://10.0.0.2:4243", 2, nil},
+		{"unix:///var/run/docker.sock", 1, nil},
+		{"10.0.0.2:4243", 2, ErrInvalidNodeAddress},
+		{"tcp://", 2, ErrInvalidNodeAddress},
+		{"", 2, ErrInvalidNodeAddress},
+		{"tcp://10.0.0.2:4243", 0, ErrInvalidNodeConcurrency},
+	}
+
+	for _, test := range tests {
+		node := Node{Address: test.address, Concurrency: test.concurrency}
+		if err := node.Validate(); err != test.err {
+			t.Errorf("Expected node %s with concurrency %d to return %v, got %v", test.address, test.concurrency, test.err, err)
+		}
+	}
+}
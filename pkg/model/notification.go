@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+)
+
+// Notification represents the result of sending a
+// notification, such as an email or IRC message, for
+// a build.
+type Notification struct {
+	ID      int64 `meddler:"id,pk"    json:"id"`
+	RepoID  int64 `meddler:"repo_id"  json:"-"`
+	BuildID int64 `meddler:"build_id" json:"build_id"`
+
+	// Notifier is the type of notification, such as
+	// email, webhook, hipchat, irc or slack.
+	Notifier string `meddler:"notifier" json:"notifier"`
+
+	// Target describes where the notification was sent,
+	// such as the email recipients or the IRC channels.
+	Target string `meddler:"target" json:"target"`
+
+	// Event is the status of the build that triggered
+	// the notification, such as Started or Failure.
+	Event string `meddler:"event" json:"event"`
+
+	// Status is Success if the notification was sent,
+	// or Failure if the last attempt failed.
+	Status string `meddler:"status" json:"status"`
+
+	// Error describes why the last attempt failed.
+	Error string `meddler:"error" json:"error"`
+
+	// Attempts is the number of times the notification
+	// was sent, since failed notifications can be retried.
+	Attempts int `meddler:"attempts" json:"attempts"`
+
+	Created time.Time `meddler:"created,utctime" json:"created"`
+	Updated time.Time `meddler:"updated,utctime" json:"updated"`
+}
+
+// IsSuccess returns true if the notification
+// was sent.
+func (n *Notification) IsSuccess() bool {
+	return n.Status == StatusSuccess
+}
+
+// Returns the Updated Date, which is the time of
+// the last attempt, as an ISO8601 formatted string.
+func (n *Notification) UpdatedString() string {
+	return n.Updated.Format("2006-01-02T15:04:05Z")
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -72,17 +73,21 @@ type Repo struct {
 	// username and password requires to authenticate
 	// to the repository
 	Username string `meddler:"username" json:"username"`
-	Password string `meddler:"password" json:"password"`
+	Password string `meddler:"password" json:"-"`
 
 	// RSA key pair that will injected into the virtual machine
 	// .ssh/id_rsa and .ssh/id_rsa.pub files.
 	PublicKey  string `meddler:"public_key"  json:"public_key"`
-	PrivateKey string `meddler:"private_key" json:"public_key"`
+	PrivateKey string `meddler:"private_key" json:"-"`
 
 	// Parameters stored external to the repository in YAML
 	// format, injected into the Build YAML at runtime.
 	Params map[string]string `meddler:"params,gob" json:"-"`
 
+	// Downstream lists the repository slugs, one per line, that
+	// should be built after a successful build of this repository.
+	Downstream string `meddler:"downstream" json:"downstream"`
+
 	// the amount of time, in seconds the build will execute
 	// before exceeding its timelimit and being killed.
 	Timeout int64 `meddler:"timeout" json:"timeout"`
@@ -91,6 +96,14 @@ type Repo struct {
 	// mode. This could, for example, be used to run Docker in Docker.
 	Privileged bool `meddler:"privileged" json:"privileged"`
 
+	// Resource limits of the build and service containers,
+	// which override the system defaults. Memory and swap
+	// are in megabytes, and a value of 0 means the system
+	// default is used.
+	Memory    int64 `meddler:"memory"     json:"memory"`
+	Swap      int64 `meddler:"swap"       json:"swap"`
+	CpuShares int64 `meddler:"cpu_shares" json:"cpu_shares"`
+
 	// Foreign keys signify the User that created
 	// the repository and team account linked to
 	// the repository.
@@ -144,6 +157,17 @@ func NewBitbucketRepo(owner, name string, private bool) (*Repo, error) {
 	return NewRepo(HostBitbucket, owner, name, ScmGit, url)
 }
 
+// Returns the list of downstream repository slugs.
+func (r *Repo) DownstreamList() []string {
+	return strings.Fields(strings.Replace(r.Downstream, ",", " ", -1))
+}
+
+// ValidateLimits verifies the resource limits
+// of the repository are correctly populated.
+func (r *Repo) ValidateLimits() error {
+	return validateLimits(r.Memory, r.Swap, r.CpuShares)
+}
+
 func (r *Repo) DefaultBranch() string {
 	switch r.SCM {
 	case ScmGit:
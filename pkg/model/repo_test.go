@@ -0,0 +1,19 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_RepoDownstreamList(t *testing.T) {
+	repo := Repo{}
+	if list := repo.DownstreamList(); len(list) != 0 {
+		t.Errorf("Expecting empty downstream list, got %v", list)
+	}
+
+	repo.Downstream = "github.com/drone/go-github\r\n github.com/drone/drone, github.com/drone/routes\n"
+	want := []string{"github.com/drone/go-github", "github.com/drone/drone", "github.com/drone/routes"}
+	if list := repo.DownstreamList(); !reflect.DeepEqual(list, want) {
+		t.Errorf("Expecting downstream list %v, got %v", want, list)
+	}
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/drone/drone/pkg/cron"
+)
+
+var (
+	ErrInvalidScheduleBranch = errors.New("Schedule Branch must be provided")
+	ErrInvalidScheduleSpec   = errors.New("Invalid Schedule, expected a cron expression such as 0 0 * * *")
+)
+
+// Schedule represents a time-based (cron) trigger
+// that periodically builds the head of a branch.
+type Schedule struct {
+	ID     int64  `meddler:"id,pk"   json:"id"`
+	RepoID int64  `meddler:"repo_id" json:"-"`
+	Branch string `meddler:"branch"  json:"branch"`
+
+	// Spec is a standard five field cron expression,
+	// for example "0 2 * * *" runs nightly at 2am, UTC.
+	Spec string `meddler:"spec" json:"spec"`
+
+	// LastRun is the last time the schedule
+	// triggered a build.
+	LastRun time.Time `meddler:"last_run,utctime" json:"last_run"`
+
+	Created time.Time `meddler:"created,utctime" json:"created"`
+	Updated time.Time `meddler:"updated,utctime" json:"updated"`
+}
+
+// Validate verifies all required fields are correctly populated.
+func (s *Schedule) Validate() error {
+	switch {
+	case len(s.Branch) == 0:
+		return ErrInvalidScheduleBranch
+	default:
+		if _, err := cron.Parse(s.Spec); err != nil {
+			return ErrInvalidScheduleSpec
+		}
+		return nil
+	}
+}
+
+// IsDue returns true if the schedule should trigger
+// a build at time t, and has not already done so.
+func (s *Schedule) IsDue(t time.Time) bool {
+	expr, err := cron.Parse(s.Spec)
+	if err != nil {
+		return false
+	}
+	t = t.UTC().Truncate(time.Minute)
+	return expr.Matches(t) && s.LastRun.Before(t)
+}
+
+// NextRun returns the next time the schedule
+// will trigger a build.
+func (s *Schedule) NextRun() time.Time {
+	expr, err := cron.Parse(s.Spec)
+	if err != nil {
+		return time.Time{}
+	}
+	return expr.Next(time.Now().UTC())
+}
+
+// Returns the LastRun Date as an ISO8601
+// formatted string.
+func (s *Schedule) LastRunString() string {
+	return s.LastRun.Format("2006-01-02T15:04:05Z")
+}
+
+// Returns the NextRun Date as an ISO8601
+// formatted string, or an empty string if the
+// schedule never runs.
+func (s *Schedule) NextRunString() string {
+	next := s.NextRun()
+	if next.IsZero() {
+		return ""
+	}
+	return next.Format("2006-01-02T15:04:05Z")
+}
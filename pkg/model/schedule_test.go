@@ -0,0 +1,51 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleValidate(t *testing.T) {
+	schedule := Schedule{Branch: "master", Spec: "0 0 * * *"}
+	if err := schedule.Validate(); err != nil {
+		t.Errorf("Expected valid schedule, got %s", err)
+	}
+
+	schedule = Schedule{Spec: "0 0 * * *"}
+	if err := schedule.Validate(); err != ErrInvalidScheduleBranch {
+		t.Errorf("Expected ErrInvalidScheduleBranch, got %v", err)
+	}
+
+	schedule = Schedule{Branch: "master", Spec: "every day"}
+	if err := schedule.Validate(); err != ErrInvalidScheduleSpec {
+		t.Errorf("Expected ErrInvalidScheduleSpec, got %v", err)
+	}
+
+	// a schedule that never runs is refused
+	schedule = Schedule{Branch: "master", Spec: "0 0 30 2 *"}
+	if err := schedule.Validate(); err != ErrInvalidScheduleSpec {
+		t.Errorf("Expected ErrInvalidScheduleSpec, got %v", err)
+	}
+	if next := schedule.NextRunString(); len(next) != 0 {
+		t.Errorf("Expected no next run, got %s", next)
+	}
+}
+
+func TestScheduleIsDue(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2014-03-10T00:00:20Z")
+	schedule := Schedule{Branch: "master", Spec: "0 0 * * *"}
+	if !schedule.IsDue(now) {
+		t.Errorf("Expected schedule to be due")
+	}
+
+	// the schedule already ran this minute
+	schedule.LastRun = now.Truncate(time.Minute)
+	if schedule.IsDue(now) {
+		t.Errorf("Expected schedule to not be due after running")
+	}
+
+	// the expression does not match
+	if schedule.IsDue(now.Add(time.Hour)) {
+		t.Errorf("Expected schedule to not be due at %s", now.Add(time.Hour))
+	}
+}
@@ -10,6 +10,33 @@ var (
 	ErrInvalidGitHubTrailingSlash = errors.New("GitHub URL should not have a trailing slash")
 	ErrInvalidSmtpAddress         = errors.New("SMTP From Address must be provided")
 	ErrInvalidSmtpPort            = errors.New("SMTP Port must be provided")
+	ErrInvalidSmtpSecurity        = errors.New("SMTP Security must be none, starttls or tls")
+	ErrInvalidSmtpAuth            = errors.New("SMTP Authentication must be plain, cram-md5 or none")
+	ErrInvalidMemoryLimit         = errors.New("Memory limit must not be negative")
+	ErrInvalidSwapLimit           = errors.New("Swap limit must not be negative, or -1 to disable swap")
+	ErrInvalidCpuShares           = errors.New("CPU shares must not be negative")
+)
+
+// SMTP connection security modes.
+const (
+	// SmtpSecurityNone upgrades the connection with
+	// STARTTLS only if the server supports it.
+	SmtpSecurityNone = ""
+
+	// SmtpSecurityStartTLS requires the server to
+	// support STARTTLS.
+	SmtpSecurityStartTLS = "starttls"
+
+	// SmtpSecurityTLS connects with implicit TLS,
+	// usually on port 465.
+	SmtpSecurityTLS = "tls"
+)
+
+// SMTP authentication mechanisms.
+const (
+	SmtpAuthPlain   = ""
+	SmtpAuthCramMD5 = "cram-md5"
+	SmtpAuthNone    = "none"
 )
 
 type Settings struct {
@@ -21,6 +48,8 @@ type Settings struct {
 	SmtpAddress  string `meddler:"smtp_address"`
 	SmtpUsername string `meddler:"smtp_username"`
 	SmtpPassword string `meddler:"smtp_password"`
+	SmtpSecurity string `meddler:"smtp_security"`
+	SmtpAuth     string `meddler:"smtp_auth"`
 
 	// GitHub Consumer key and secret.
 	GitHubKey    string `meddler:"github_key"`
@@ -39,6 +68,13 @@ type Settings struct {
 	Scheme string `meddler:"scheme"`
 
 	OpenInvitations bool `meddler:"open_invitations"`
+
+	// Default resource limits of the build and
+	// service containers. Memory and swap are in
+	// megabytes, and a value of 0 means no limit.
+	BuildMemory    int64 `meddler:"build_memory"`
+	BuildSwap      int64 `meddler:"build_swap"`
+	BuildCpuShares int64 `meddler:"build_cpu_shares"`
 }
 
 func (s *Settings) URL() *url.URL {
@@ -56,6 +92,25 @@ func (s *Settings) Validate() error {
 		return ErrInvalidSmtpPort
 	case len(s.SmtpServer) != 0 && len(s.SmtpAddress) == 0:
 		return ErrInvalidSmtpAddress
+	case s.SmtpSecurity != SmtpSecurityNone && s.SmtpSecurity != SmtpSecurityStartTLS && s.SmtpSecurity != SmtpSecurityTLS:
+		return ErrInvalidSmtpSecurity
+	case s.SmtpAuth != SmtpAuthPlain && s.SmtpAuth != SmtpAuthCramMD5 && s.SmtpAuth != SmtpAuthNone:
+		return ErrInvalidSmtpAuth
+	default:
+		return validateLimits(s.BuildMemory, s.BuildSwap, s.BuildCpuShares)
+	}
+}
+
+// validateLimits verifies the memory and swap, in
+// megabytes, and the CPU shares of a build.
+func validateLimits(memory, swap, cpuShares int64) error {
+	switch {
+	case memory < 0:
+		return ErrInvalidMemoryLimit
+	case swap < -1:
+		return ErrInvalidSwapLimit
+	case cpuShares < 0:
+		return ErrInvalidCpuShares
 	default:
 		return nil
 	}
@@ -32,4 +32,42 @@ func Test_SettingsValidate(t *testing.T) {
 	if err := settings.Validate(); err != nil {
 		t.Errorf("Expecting successful Settings validation, got %s", err)
 	}
+
+	settings = Settings{}
+	settings.SmtpSecurity = "ssl"
+	if err := settings.Validate(); err != ErrInvalidSmtpSecurity {
+		t.Errorf("Expecting ErrInvalidSmtpSecurity")
+	}
+
+	settings = Settings{}
+	settings.SmtpAuth = "login"
+	if err := settings.Validate(); err != ErrInvalidSmtpAuth {
+		t.Errorf("Expecting ErrInvalidSmtpAuth")
+	}
+
+	settings = Settings{}
+	settings.BuildMemory = -1
+	if err := settings.Validate(); err != ErrInvalidMemoryLimit {
+		t.Errorf("Expecting ErrInvalidMemoryLimit")
+	}
+
+	settings = Settings{}
+	settings.BuildSwap = -2
+	if err := settings.Validate(); err != ErrInvalidSwapLimit {
+		t.Errorf("Expecting ErrInvalidSwapLimit")
+	}
+
+	settings = Settings{}
+	settings.BuildCpuShares = -1
+	if err := settings.Validate(); err != ErrInvalidCpuShares {
+		t.Errorf("Expecting ErrInvalidCpuShares")
+	}
+
+	settings = Settings{}
+	settings.BuildMemory = 512
+	settings.BuildSwap = -1
+	settings.BuildCpuShares = 512
+	if err := settings.Validate(); err != nil {
+		t.Errorf("Expecting successful Settings validation, got %s", err)
+	}
 }
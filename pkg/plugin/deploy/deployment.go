@@ -1,9 +1,18 @@
 package deploy
 
 import (
+	"errors"
+
 	"github.com/drone/drone/pkg/build/buildfile"
 )
 
+var (
+	ErrMissingApp     = errors.New("app must be provided")
+	ErrMissingProject = errors.New("project must be provided")
+	ErrMissingTarget  = errors.New("target must be provided")
+	ErrMissingToken   = errors.New("token must be provided")
+)
+
 // Deploy stores the configuration details
 // for deploying build artifacts when
 // a Build has succeeded
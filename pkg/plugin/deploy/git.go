@@ -11,6 +11,14 @@ type Git struct {
 	Branch string `yaml:"branch,omitempty"`
 }
 
+// Validate verifies all required fields are correctly populated.
+func (g *Git) Validate() error {
+	if len(g.Target) == 0 {
+		return ErrMissingTarget
+	}
+	return nil
+}
+
 func (g *Git) Write(f *buildfile.Buildfile) {
 	// get the current commit hash
 	f.WriteCmdSilent("COMMIT=$(git rev-parse HEAD)")
@@ -21,7 +29,7 @@ func (g *Git) Write(f *buildfile.Buildfile) {
 	f.WriteCmdSilent("git config --global user.email $(git --no-pager log -1 --pretty=format:'%ae')")
 
 	// add target as a git remote
-	f.WriteCmd(fmt.Sprintf("git remote add deploy %s", g.Target))
+	f.WriteCmd(fmt.Sprintf("git remote add deploy %s", buildfile.Quote(g.Target)))
 
 	destinationBranch := g.Branch
 	if destinationBranch == "" {
@@ -35,9 +43,9 @@ func (g *Git) Write(f *buildfile.Buildfile) {
 		// that need to be deployed to git remote.
 		f.WriteCmd(fmt.Sprintf("git add -A"))
 		f.WriteCmd(fmt.Sprintf("git commit -m 'add build artifacts'"))
-		f.WriteCmd(fmt.Sprintf("git push deploy $COMMIT:%s --force", destinationBranch))
+		f.WriteCmd(fmt.Sprintf("git push deploy $COMMIT:%s --force", buildfile.Quote(destinationBranch)))
 	case false:
 		// otherwise we just do a standard git push
-		f.WriteCmd(fmt.Sprintf("git push deploy $COMMIT:%s", destinationBranch))
+		f.WriteCmd(fmt.Sprintf("git push deploy $COMMIT:%s", buildfile.Quote(destinationBranch)))
 	}
 }
@@ -11,6 +11,14 @@ type Heroku struct {
 	Branch string `yaml:"branch,omitempty"`
 }
 
+// Validate verifies all required fields are correctly populated.
+func (h *Heroku) Validate() error {
+	if len(h.App) == 0 {
+		return ErrMissingApp
+	}
+	return nil
+}
+
 func (h *Heroku) Write(f *buildfile.Buildfile) {
 	// get the current commit hash
 	f.WriteCmdSilent("COMMIT=$(git rev-parse HEAD)")
@@ -21,7 +29,7 @@ func (h *Heroku) Write(f *buildfile.Buildfile) {
 	f.WriteCmdSilent("git config --global user.email $(git --no-pager log -1 --pretty=format:'%ae')")
 
 	// add heroku as a git remote
-	f.WriteCmd(fmt.Sprintf("git remote add heroku git@heroku.com:%s.git", h.App))
+	f.WriteCmd(fmt.Sprintf("git remote add heroku %s", buildfile.Quote("git@heroku.com:"+h.App+".git")))
 
 	switch h.Force {
 	case true:
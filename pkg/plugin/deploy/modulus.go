@@ -10,6 +10,18 @@ type Modulus struct {
 	Token   string `yaml:"token,omitempty"`
 }
 
+// Validate verifies all required fields are correctly populated.
+func (m *Modulus) Validate() error {
+	switch {
+	case len(m.Project) == 0:
+		return ErrMissingProject
+	case len(m.Token) == 0:
+		return ErrMissingToken
+	default:
+		return nil
+	}
+}
+
 func (m *Modulus) Write(f *buildfile.Buildfile) {
 	f.WriteEnv("MODULUS_TOKEN", m.Token)
 
@@ -17,5 +29,5 @@ func (m *Modulus) Write(f *buildfile.Buildfile) {
 	// project.
 	f.WriteCmdSilent("[ -f /usr/bin/sudo ] || npm install -g modulus")
 	f.WriteCmdSilent("[ -f /usr/bin/sudo ] && sudo npm install -g modulus")
-	f.WriteCmd(fmt.Sprintf("modulus deploy -p '%s'", m.Project))
+	f.WriteCmd(fmt.Sprintf("modulus deploy -p %s", buildfile.Quote(m.Project)))
 }
@@ -43,6 +43,14 @@ type SSH struct {
 	Cmd string `yaml:"cmd,omitempty"`
 }
 
+// Validate verifies all required fields are correctly populated.
+func (s *SSH) Validate() error {
+	if len(s.Target) == 0 {
+		return ErrMissingTarget
+	}
+	return nil
+}
+
 // Write down the buildfile
 func (s *SSH) Write(f *buildfile.Buildfile) {
 	host := strings.SplitN(s.Target, " ", 2)
@@ -66,18 +74,21 @@ func (s *SSH) Write(f *buildfile.Buildfile) {
 	if len(s.Artifacts) > 1 && !artifact {
 		artifact = compress(f, s.Artifacts)
 	} else if len(s.Artifacts) == 1 {
-		f.WriteCmdSilent(fmt.Sprintf("ARTIFACT=%s", s.Artifacts[0]))
+		f.WriteCmdSilent(fmt.Sprintf("ARTIFACT=%s", buildfile.Quote(s.Artifacts[0])))
 		artifact = true
 	}
 
 	if artifact {
 		scpCmd := "scp -o StrictHostKeyChecking=no -P %s ${ARTIFACT} %s"
-		f.WriteCmd(fmt.Sprintf(scpCmd, host[1], host[0]))
+		f.WriteCmd(fmt.Sprintf(scpCmd, host[1], buildfile.Quote(host[0])))
 	}
 
+	// the command is quoted so that it is passed to the
+	// target host as a single argument, and evaluated by
+	// the remote shell instead of the build script.
 	if len(s.Cmd) > 0 {
 		sshCmd := "ssh -o StrictHostKeyChecking=no -p %s %s %s"
-		f.WriteCmd(fmt.Sprintf(sshCmd, host[1], strings.SplitN(host[0], ":", 2)[0], s.Cmd))
+		f.WriteCmd(fmt.Sprintf(sshCmd, host[1], buildfile.Quote(strings.SplitN(host[0], ":", 2)[0]), buildfile.Quote(s.Cmd)))
 	}
 }
 
@@ -89,8 +100,15 @@ func createGitArchive(f *buildfile.Buildfile) bool {
 }
 
 func compress(f *buildfile.Buildfile, files []string) bool {
+	// artifacts may use wildcards, such as dist/*.jar,
+	// which are expanded by the shell.
+	var args []string
+	for _, file := range files {
+		args = append(args, buildfile.QuoteGlob(file))
+	}
+
 	cmd := "tar -cf ${ARTIFACT} %s"
 	f.WriteCmdSilent("ARTIFACT=${PWD##*/}.tar.gz")
-	f.WriteCmdSilent(fmt.Sprintf(cmd, strings.Join(files, " ")))
+	f.WriteCmdSilent(fmt.Sprintf(cmd, strings.Join(args, " ")))
 	return true
 }
@@ -123,3 +123,28 @@ func TestSSHGitArchive(t *testing.T) {
 		t.Errorf("Expect script to run git archive")
 	}
 }
+
+var sampleYml4 = `
+deploy:
+  ssh:
+    target: user@test.example.com:/srv/app/location 2212
+    artifacts:
+      - build result
+      - dist/*.jar
+    cmd: cd /srv/app && ./restart.sh "$(date)"
+`
+
+func TestSSHQuoted(t *testing.T) {
+	bscr, err := setUp(sampleYml4)
+	if err != nil {
+		t.Fatalf("Can't unmarshal deploy script: %s", err)
+	}
+
+	if !strings.Contains(bscr, "tar -cf ${ARTIFACT} 'build result' dist/*.jar") {
+		t.Errorf("Expect script to contain quoted tar command. got:\n%s", bscr)
+	}
+
+	if !strings.Contains(bscr, `ssh -o StrictHostKeyChecking=no -p 2212 user@test.example.com 'cd /srv/app && ./restart.sh "$(date)"'`) {
+		t.Errorf("Expect script to contain quoted ssh command. got:\n%s", bscr)
+	}
+}
@@ -1,32 +1,70 @@
 package notify
 
-import "github.com/drone/drone/pkg/mail"
+import (
+	"strings"
+
+	"github.com/drone/drone/pkg/mail"
+)
 
 type Email struct {
 	Recipients []string `yaml:"recipients,omitempty"`
 	Success    string   `yaml:"on_success"`
 	Failure    string   `yaml:"on_failure"`
+	Change     bool     `yaml:"on_change,omitempty"`
+	Fixed      bool     `yaml:"on_fixed,omitempty"`
+	Broken     bool     `yaml:"on_broken,omitempty"`
+
+	// Author also notifies the author of the commit.
+	Author bool `yaml:"author,omitempty"`
+
+	// Template is an optional html/template used as
+	// the body of the email instead of the default.
+	Template string `yaml:"template,omitempty"`
 }
 
 // Send will send an email, either success or failure,
 // based on the Commit Status.
 func (e *Email) Send(context *Context) error {
-	switch {
-	case context.Commit.Status == "Success" && e.Success != "never":
+	if !e.enabled(context) {
+		return nil
+	}
+	return e.sendStatus(context)
+}
+
+// enabled returns true for finished builds, unless
+// disabled with never. Emails are not sent when a
+// build is started.
+func (e *Email) enabled(context *Context) bool {
+	switch context.Commit.Status {
+	case "Success":
+		return e.Success != "never" || onChange(context, e.Change, e.Fixed, e.Broken)
+	case "Failure":
+		return e.Failure != "never" || onChange(context, e.Change, e.Fixed, e.Broken)
+	}
+	return false
+}
+
+func (e *Email) sendStatus(context *Context) error {
+	if context.Commit.Status == "Success" {
 		return e.sendSuccess(context)
-	case context.Commit.Status == "Failure" && e.Failure != "never":
-		return e.sendFailure(context)
 	}
+	return e.sendFailure(context)
+}
 
-	return nil
+func (e *Email) target(context *Context) string {
+	return strings.Join(e.recipients(context), ", ")
 }
 
 // sendFailure sends email notifications to the list of
 // recipients indicating the build failed.
 func (e *Email) sendFailure(context *Context) error {
+	if hasTemplate(e.Template, context) {
+		return e.sendTemplate("[FAILURE] "+context.Repo.Name, context)
+	}
+
 	// loop through and email recipients
-	for _, email := range e.Recipients {
-		if err := mail.SendFailure(context.Repo.Name, email, context); err != nil {
+	for _, email := range e.recipients(context) {
+		if err := mail.SendFailure(context.Repo.Name, email, newMessage(context)); err != nil {
 			return err
 		}
 	}
@@ -36,11 +74,75 @@ func (e *Email) sendFailure(context *Context) error {
 // sendSuccess sends email notifications to the list of
 // recipients indicating the build was a success.
 func (e *Email) sendSuccess(context *Context) error {
+	if hasTemplate(e.Template, context) {
+		return e.sendTemplate("[SUCCESS] "+context.Repo.Name, context)
+	}
+
+	// loop through and email recipients
+	for _, email := range e.recipients(context) {
+		if err := mail.SendSuccess(context.Repo.Name, email, newMessage(context)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendTemplate sends email notifications to the list of
+// recipients using the user-defined template.
+func (e *Email) sendTemplate(subject string, context *Context) error {
+	body, err := renderHTML(e.Template, context)
+	if err != nil {
+		return err
+	}
+
 	// loop through and email recipients
-	for _, email := range e.Recipients {
-		if err := mail.SendSuccess(context.Repo.Name, email, context); err != nil {
+	for _, email := range e.recipients(context) {
+		msg := mail.Message{To: email, Subject: subject, Body: body}
+		if err := mail.Send(&msg); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// recipients returns the list of recipients, including
+// the author of the commit if enabled and known.
+func (e *Email) recipients(context *Context) []string {
+	recipients := e.Recipients
+	if !e.Author {
+		return recipients
+	}
+
+	author := authorEmail(context)
+	if len(author) == 0 {
+		return recipients
+	}
+	for _, email := range recipients {
+		if strings.EqualFold(email, author) {
+			return recipients
+		}
+	}
+	return append(recipients[:len(recipients):len(recipients)], author)
+}
+
+// authorEmail returns the email address of the commit
+// author. The author of a push is an email address, and
+// the author of a pull request is a login, which is only
+// known if the author is a Drone user.
+func authorEmail(context *Context) string {
+	author := context.Commit.Author
+	switch {
+	case len(author) == 0:
+		return ""
+	case strings.Contains(author, "@"):
+		return author
+	case context.FindUser == nil:
+		return ""
+	}
+
+	user, err := context.FindUser(author)
+	if err != nil {
+		return ""
+	}
+	return user.Email
+}
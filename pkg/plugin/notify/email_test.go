@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/drone/drone/pkg/model"
+)
+
+func TestEmailRecipients(t *testing.T) {
+	findUser := func(login string) (*model.User, error) {
+		if login == "bradrydzewski" {
+			return &model.User{Email: "brad@drone.io"}, nil
+		}
+		return nil, sql.ErrNoRows
+	}
+
+	var tests = []struct {
+		author     bool
+		commit     string
+		recipients []string
+	}{
+		// the author is not notified by default
+		{false, "burke@drone.io", []string{"team@drone.io"}},
+		// the author of a push is an email address
+		{true, "burke@drone.io", []string{"team@drone.io", "burke@drone.io"}},
+		// the author is not notified twice
+		{true, "Team@drone.io", []string{"team@drone.io"}},
+		// the author of a pull request is a login
+		{true, "bradrydzewski", []string{"team@drone.io", "brad@drone.io"}},
+		// the author is not a Drone user
+		{true, "octocat", []string{"team@drone.io"}},
+	}
+
+	for _, test := range tests {
+		email := &Email{Recipients: []string{"team@drone.io"}, Author: test.author}
+		context := &Context{Commit: &model.Commit{Author: test.commit}, FindUser: findUser}
+		if got := email.recipients(context); !reflect.DeepEqual(got, test.recipients) {
+			t.Errorf("Expected recipients %v for author %s, got %v", test.recipients, test.commit, got)
+		}
+		if len(email.Recipients) != 1 {
+			t.Errorf("Expected the configured recipients to be unchanged, got %v", email.Recipients)
+		}
+	}
+}
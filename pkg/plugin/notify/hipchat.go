@@ -1,9 +1,13 @@
 package notify
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-
-	"github.com/andybons/hipchat"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
 )
 
 const (
@@ -12,53 +16,174 @@ const (
 	failureMessage = "<b>Failed</b> %s, commit %s, author %s"
 )
 
+// hipchatServer is the URL of the hosted HipChat
+// service, used unless a server is configured.
+const hipchatServer = "https://api.hipchat.com"
+
+// hipchatColors are the default message colors
+// of each status.
+var hipchatColors = map[string]string{
+	"started": "yellow",
+	"success": "green",
+	"failure": "red",
+}
+
 type Hipchat struct {
-	Room    string `yaml:"room,omitempty"`
-	Token   string `yaml:"token,omitempty"`
-	Started bool   `yaml:"on_started,omitempty"`
-	Success bool   `yaml:"on_success,omitempty"`
-	Failure bool   `yaml:"on_failure,omitempty"`
+	// Server is the URL of a self-hosted HipChat
+	// server, such as https://hipchat.example.com
+	Server string `yaml:"server,omitempty"`
+
+	// Room is the name or id of the room, and Token
+	// is a room notification token of the room.
+	Room  string `yaml:"room,omitempty"`
+	Token string `yaml:"token,omitempty"`
+
+	// From is the name of the sender.
+	From string `yaml:"from,omitempty"`
+
+	Started bool `yaml:"on_started,omitempty"`
+	Success bool `yaml:"on_success,omitempty"`
+	Failure bool `yaml:"on_failure,omitempty"`
+	Change  bool `yaml:"on_change,omitempty"`
+	Fixed   bool `yaml:"on_fixed,omitempty"`
+	Broken  bool `yaml:"on_broken,omitempty"`
+
+	// Colors overrides the message color of each status,
+	// which are started, success and failure. The color
+	// is yellow, green, red, purple, gray or random.
+	Colors map[string]string `yaml:"colors,omitempty"`
+
+	// Notify controls whether the message of each status
+	// notifies the users in the room, which it does by
+	// default.
+	Notify map[string]bool `yaml:"notify,omitempty"`
+
+	// Template is an optional text/template used
+	// instead of the default message.
+	Template string `yaml:"template,omitempty"`
 }
 
 func (h *Hipchat) Send(context *Context) error {
-	switch {
-	case context.Commit.Status == "Started" && h.Started:
+	if !h.enabled(context) {
+		return nil
+	}
+	return h.sendStatus(context)
+}
+
+func (h *Hipchat) enabled(context *Context) bool {
+	switch context.Commit.Status {
+	case "Started":
+		return h.Started
+	case "Success":
+		return h.Success || onChange(context, h.Change, h.Fixed, h.Broken)
+	case "Failure":
+		return h.Failure || onChange(context, h.Change, h.Fixed, h.Broken)
+	}
+	return false
+}
+
+func (h *Hipchat) sendStatus(context *Context) error {
+	switch context.Commit.Status {
+	case "Started":
 		return h.sendStarted(context)
-	case context.Commit.Status == "Success" && h.Success:
+	case "Success":
 		return h.sendSuccess(context)
-	case context.Commit.Status == "Failure" && h.Failure:
-		return h.sendFailure(context)
 	}
+	return h.sendFailure(context)
+}
 
-	return nil
+func (h *Hipchat) target(context *Context) string {
+	return h.Room
 }
 
 func (h *Hipchat) sendStarted(context *Context) error {
 	msg := fmt.Sprintf(startedMessage, context.Repo.Name, context.Commit.HashShort(), context.Commit.Author)
-	return h.send(hipchat.ColorYellow, hipchat.FormatHTML, msg)
+	return h.sendMessage(context, "started", msg)
 }
 
 func (h *Hipchat) sendFailure(context *Context) error {
 	msg := fmt.Sprintf(failureMessage, context.Repo.Name, context.Commit.HashShort(), context.Commit.Author)
-	return h.send(hipchat.ColorRed, hipchat.FormatHTML, msg)
+	return h.sendMessage(context, "failure", msg)
 }
 
 func (h *Hipchat) sendSuccess(context *Context) error {
 	msg := fmt.Sprintf(successMessage, context.Repo.Name, context.Commit.HashShort(), context.Commit.Author)
-	return h.send(hipchat.ColorGreen, hipchat.FormatHTML, msg)
+	return h.sendMessage(context, "success", msg)
+}
+
+// sendMessage sends the HTML message, or the user-defined
+// template as plain text, if any.
+func (h *Hipchat) sendMessage(context *Context, status, msg string) error {
+	if !hasTemplate(h.Template, context) {
+		return h.send(status, "html", msg)
+	}
+	text, err := render(h.Template, context)
+	if err != nil {
+		return err
+	}
+	return h.send(status, "text", text)
+}
+
+// hipchatMessage is the payload of a HipChat v2
+// room notification.
+type hipchatMessage struct {
+	From          string `json:"from,omitempty"`
+	Message       string `json:"message"`
+	MessageFormat string `json:"message_format"`
+	Color         string `json:"color"`
+	Notify        bool   `json:"notify"`
 }
 
 // helper function to send Hipchat requests
-func (h *Hipchat) send(color, format, message string) error {
-	c := hipchat.Client{AuthToken: h.Token}
-	req := hipchat.MessageRequest{
-		RoomId:        h.Room,
-		From:          "Drone",
+func (h *Hipchat) send(status, format, message string) error {
+	msg := hipchatMessage{
+		From:          h.From,
 		Message:       message,
-		Color:         color,
 		MessageFormat: format,
+		Color:         hipchatColors[status],
 		Notify:        true,
 	}
+	if len(msg.From) == 0 {
+		msg.From = "Drone"
+	}
+	if color, ok := h.Colors[status]; ok {
+		msg.Color = color
+	}
+	if notify, ok := h.Notify[status]; ok {
+		msg.Notify = notify
+	}
+
+	payload, err := json.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+
+	server := strings.TrimRight(h.Server, "/")
+	if len(server) == 0 {
+		server = hipchatServer
+	}
+	// the room name is escaped as a single
+	// segment of the path.
+	room := (&url.URL{Path: h.Room}).String()
+	room = strings.Replace(strings.TrimPrefix(room, "./"), "/", "%2F", -1)
+	endpoint := server + "/v2/room/" + room + "/notification"
+
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+h.Token)
+	req.Header.Set("Content-Type", "application/json")
 
-	return c.PostMessage(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("HipChat responded with %s: %s", resp.Status, body)
+	}
+	return nil
 }
@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drone/drone/pkg/model"
+)
+
+func TestHipchat(t *testing.T) {
+	var messages []*hipchatMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.RequestURI != "/v2/room/Drone%20Builds/notification" {
+			t.Errorf("Expected the room notification path, got %s", r.RequestURI)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer 3a2b1c" {
+			t.Errorf("Expected the room token, got %q", auth)
+		}
+		msg := hipchatMessage{}
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Error(err)
+		}
+		messages = append(messages, &msg)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	hipchat := &Hipchat{
+		Server:  server.URL + "/",
+		Room:    "Drone Builds",
+		Token:   "3a2b1c",
+		Started: true,
+		Success: true,
+		Failure: true,
+		Colors:  map[string]string{"success": "purple"},
+		Notify:  map[string]bool{"started": false},
+	}
+	context := &Context{
+		Repo:   &model.Repo{Name: "drone"},
+		Commit: &model.Commit{Hash: "4f4c45b1d8a0", Author: "brad@drone.io"},
+	}
+
+	var tests = []struct {
+		status string
+		color  string
+		notify bool
+	}{
+		{"Started", "yellow", false},
+		{"Success", "purple", true},
+		{"Failure", "red", true},
+	}
+
+	for _, test := range tests {
+		messages = nil
+		context.Commit.Status = test.status
+		if err := hipchat.Send(context); err != nil {
+			t.Fatal(err)
+		}
+		if len(messages) != 1 {
+			t.Fatalf("Expected one message for status %s, got %d", test.status, len(messages))
+		}
+		msg := messages[0]
+		if msg.Color != test.color {
+			t.Errorf("Expected color %s for status %s, got %s", test.color, test.status, msg.Color)
+		}
+		if msg.Notify != test.notify {
+			t.Errorf("Expected notify %v for status %s, got %v", test.notify, test.status, msg.Notify)
+		}
+		if msg.From != "Drone" {
+			t.Errorf("Expected the default sender, got %q", msg.From)
+		}
+		if msg.MessageFormat != "html" {
+			t.Errorf("Expected an html message, got %s", msg.MessageFormat)
+		}
+	}
+
+	// the sender is configurable, and a template
+	// is sent as plain text.
+	messages = nil
+	hipchat.From = "CI"
+	hipchat.Template = "{{ .Repo.Name }} {{ .Commit.Status }}"
+	if err := hipchat.Send(context); err != nil {
+		t.Fatal(err)
+	}
+	if msg := messages[0]; msg.From != "CI" || msg.MessageFormat != "text" || msg.Message != "drone Failure" {
+		t.Errorf("Expected a plain text message from CI, got %+v", msg)
+	}
+}
+
+func TestHipchatError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": {"message": "Invalid OAuth session"}}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	hipchat := &Hipchat{Server: server.URL, Room: "drone", Token: "expired", Failure: true}
+	context := &Context{
+		Repo:   &model.Repo{Name: "drone"},
+		Commit: &model.Commit{Status: "Failure", Hash: "4f4c45b1d8a0"},
+	}
+	if err := hipchat.Send(context); err == nil {
+		t.Errorf("Expected an error when HipChat rejects the token")
+	}
+}
@@ -1,84 +1,411 @@
 package notify
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
-
-	irc "github.com/fluffle/goirc/client"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
-	ircStartedMessage = "Building: %s, commit %s, author %s"
-	ircSuccessMessage = "Success: %s, commit %s, author %s"
-	ircFailureMessage = "Failed: %s, commit %s, author %s"
+	ircStartedMessage = "Building %s (%s), commit %s, author %s: %s"
+	ircSuccessMessage = "Success %s (%s), commit %s, author %s: %s"
+	ircFailureMessage = "Failed %s (%s), commit %s, author %s: %s"
 )
 
+// ircTimeout is the maximum amount of time to wait for
+// the server to accept the connection, or a message.
+var ircTimeout = 30 * time.Second
+
+// ircIdleTimeout is the amount of time an unused
+// connection is kept open, to be reused by the next
+// build.
+var ircIdleTimeout = 10 * time.Minute
+
+// ErrIRCRegister indicates the IRC server closed the
+// connection before accepting the nick.
+var ErrIRCRegister = errors.New("IRC server closed the connection before registration")
+
 type IRC struct {
-	Channel       string `yaml:"channel,omitempty"`
-	Nick          string `yaml:"nick,omitempty"`
-	Server        string `yaml:"server,omitempty"`
-	Started       bool   `yaml:"on_started,omitempty"`
-	Success       bool   `yaml:"on_success,omitempty"`
-	Failure       bool   `yaml:"on_failure,omitempty"`
-	SSL           bool   `yaml:"ssl,omitempty"`
-	ClientStarted bool
-	Client        *irc.Conn
-}
-
-func (i *IRC) Connect() {
-	c := irc.SimpleClient(i.Nick)
-	c.SSL = i.SSL
-	connected := make(chan bool)
-	c.AddHandler(irc.CONNECTED,
-		func(conn *irc.Conn, line *irc.Line) {
-			conn.Join(i.Channel)
-			connected <- true
-		})
-	c.Connect(i.Server)
-	<-connected
-	i.ClientStarted = true
-	i.Client = c
+	Server   string `yaml:"server,omitempty"`
+	SSL      bool   `yaml:"ssl,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	Nick     string `yaml:"nick,omitempty"`
+
+	// NickServ is the password used to identify
+	// the nick with NickServ.
+	NickServ string `yaml:"nickserv_password,omitempty"`
+
+	// Channel is a single channel, and Channels is
+	// a list of channels, that are notified.
+	Channel  string   `yaml:"channel,omitempty"`
+	Channels []string `yaml:"channels,omitempty"`
+
+	Started  bool   `yaml:"on_started,omitempty"`
+	Success  bool   `yaml:"on_success,omitempty"`
+	Failure  bool   `yaml:"on_failure,omitempty"`
+	Change   bool   `yaml:"on_change,omitempty"`
+	Fixed    bool   `yaml:"on_fixed,omitempty"`
+	Broken   bool   `yaml:"on_broken,omitempty"`
+	Template string `yaml:"template,omitempty"`
 }
 
 func (i *IRC) Send(context *Context) error {
-	switch {
-	case context.Commit.Status == "Started" && i.Started:
-		return i.sendStarted(context)
-	case context.Commit.Status == "Success" && i.Success:
-		return i.sendSuccess(context)
-	case context.Commit.Status == "Failure" && i.Failure:
-		return i.sendFailure(context)
+	if !i.enabled(context) {
+		return nil
 	}
-	return nil
+	return i.sendStatus(context)
 }
 
-func (i *IRC) sendStarted(context *Context) error {
-	msg := fmt.Sprintf(ircStartedMessage, context.Repo.Name, context.Commit.HashShort(), context.Commit.Author)
-	i.send(i.Channel, msg)
-	return nil
+func (i *IRC) enabled(context *Context) bool {
+	switch context.Commit.Status {
+	case "Started":
+		return i.Started
+	case "Success":
+		return i.Success || onChange(context, i.Change, i.Fixed, i.Broken)
+	case "Failure":
+		return i.Failure || onChange(context, i.Change, i.Fixed, i.Broken)
+	}
+	return false
 }
 
-func (i *IRC) sendFailure(context *Context) error {
-	msg := fmt.Sprintf(ircFailureMessage, context.Repo.Name, context.Commit.HashShort(), context.Commit.Author)
-	i.send(i.Channel, msg)
-	if i.ClientStarted {
-		i.Client.Quit()
+func (i *IRC) sendStatus(context *Context) error {
+	switch context.Commit.Status {
+	case "Started":
+		return i.sendMessage(context, ircStartedMessage)
+	case "Success":
+		return i.sendMessage(context, ircSuccessMessage)
 	}
-	return nil
+	return i.sendMessage(context, ircFailureMessage)
+}
+
+// target returns the channels, and the server.
+func (i *IRC) target(context *Context) string {
+	return strings.Join(i.channels(), ", ") + " on " + i.Server
 }
 
-func (i *IRC) sendSuccess(context *Context) error {
-	msg := fmt.Sprintf(ircSuccessMessage, context.Repo.Name, context.Commit.HashShort(), context.Commit.Author)
-	i.send(i.Channel, msg)
-	if i.ClientStarted {
-		i.Client.Quit()
+// sendMessage sends the message, or the user-defined
+// template, if any, to each channel, with one notice
+// for each line.
+func (i *IRC) sendMessage(context *Context, format string) error {
+	repo, commit := context.Repo, context.Commit
+	msg := fmt.Sprintf(format, repo.Name, commit.Branch, commit.HashShort(), commit.Author, link(context))
+	if hasTemplate(i.Template, context) {
+		text, err := render(i.Template, context)
+		if err != nil {
+			return err
+		}
+		msg = text
 	}
-	return nil
+
+	// a shared connection may have been closed by the
+	// server since it was last used, in which case the
+	// message is sent again with a new connection.
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var conn *ircConn
+		conn, err = i.connect()
+		if err != nil {
+			return err
+		}
+		if err = i.deliver(conn, msg); err == nil {
+			return nil
+		}
+		conn.close()
+	}
+	return err
 }
 
-func (i *IRC) send(channel string, message string) error {
-	if !i.ClientStarted {
-		i.Connect()
+// deliver joins each channel, and sends the message.
+func (i *IRC) deliver(conn *ircConn, msg string) error {
+	for _, channel := range i.channels() {
+		if err := conn.join(channel); err != nil {
+			return err
+		}
+		for _, line := range strings.Split(strings.TrimSpace(msg), "\n") {
+			line = ircSanitize(line)
+			if len(line) == 0 {
+				continue
+			}
+			if err := conn.notice(channel, line); err != nil {
+				return err
+			}
+		}
 	}
-	i.Client.Notice(channel, message)
 	return nil
 }
+
+// ircSanitize removes carriage returns and other control
+// characters from the line, which could otherwise be used
+// to send arbitrary commands to the server.
+func ircSanitize(line string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, line)
+}
+
+// channels returns the list of channels to notify,
+// prefixed with # if needed.
+func (i *IRC) channels() []string {
+	var channels []string
+	for _, channel := range append([]string{i.Channel}, i.Channels...) {
+		channel = strings.TrimSpace(channel)
+		switch {
+		case len(channel) == 0:
+			continue
+		case !strings.HasPrefix(channel, "#") && !strings.HasPrefix(channel, "&"):
+			channel = "#" + channel
+		}
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// ircPool stores the open connections to IRC servers, so
+// that they are shared and reused across builds, and the
+// connections that are being opened.
+var ircPool = struct {
+	sync.Mutex
+	conns   map[string]*ircConn
+	dialing map[string]*ircDial
+}{conns: map[string]*ircConn{}, dialing: map[string]*ircDial{}}
+
+// ircDial is a connection that is being opened, which
+// other builds with the same settings wait for.
+type ircDial struct {
+	done chan bool
+	conn *ircConn
+	err  error
+}
+
+// connect returns an open connection to the server, with
+// the nick, reusing an existing connection if possible.
+func (i *IRC) connect() (*ircConn, error) {
+	// connections are keyed by all the settings used to
+	// register, so that a connection is never reused by
+	// a repository with different credentials.
+	key := strings.Join([]string{i.Server, fmt.Sprint(i.SSL), i.Password, i.Nick, i.NickServ}, "\x00")
+
+	ircPool.Lock()
+	if conn, ok := ircPool.conns[key]; ok && !conn.isClosed() {
+		ircPool.Unlock()
+		return conn, nil
+	}
+	if dial, ok := ircPool.dialing[key]; ok {
+		ircPool.Unlock()
+		<-dial.done
+		return dial.conn, dial.err
+	}
+	dial := &ircDial{done: make(chan bool)}
+	ircPool.dialing[key] = dial
+	ircPool.Unlock()
+
+	// the server is dialed without holding the lock,
+	// so a slow server does not hold up the builds
+	// notifying other servers.
+	dial.conn, dial.err = dialIRC(i)
+
+	ircPool.Lock()
+	delete(ircPool.dialing, key)
+	if dial.err == nil {
+		dial.conn.key = key
+		ircPool.conns[key] = dial.conn
+	}
+	ircPool.Unlock()
+	close(dial.done)
+	return dial.conn, dial.err
+}
+
+// ircConn is a connection to an IRC server, registered
+// with a nick.
+type ircConn struct {
+	key  string
+	conn net.Conn
+	text *textproto.Conn
+
+	sync.Mutex
+	joined map[string]bool
+	closed bool
+	idle   *time.Timer
+}
+
+// dialIRC connects to the server, and registers the nick.
+func dialIRC(i *IRC) (*ircConn, error) {
+	conn, err := net.DialTimeout("tcp", i.Server, ircTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if i.SSL {
+		host, _, _ := net.SplitHostPort(i.Server)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		tlsConn.SetDeadline(time.Now().Add(ircTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	c := &ircConn{conn: conn, text: textproto.NewConn(conn), joined: map[string]bool{}}
+	if err := c.register(i); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// identify with NickServ before joining, since
+	// channels may require an identified nick.
+	if len(i.NickServ) != 0 {
+		if err := c.write("PRIVMSG NickServ :IDENTIFY %s", i.NickServ); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	c.idle = time.AfterFunc(ircIdleTimeout, c.close)
+	go c.read()
+	return c, nil
+}
+
+// register sends the server password and nick, and waits
+// until the server accepts the nick.
+func (c *ircConn) register(i *IRC) error {
+	c.conn.SetDeadline(time.Now().Add(ircTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	nick := i.Nick
+	if len(i.Password) != 0 {
+		c.text.PrintfLine("PASS %s", i.Password)
+	}
+	c.text.PrintfLine("NICK %s", nick)
+	c.text.PrintfLine("USER %s 0 * :Drone", i.Nick)
+
+	for {
+		line, err := c.text.ReadLine()
+		if err != nil {
+			return err
+		}
+		_, command, params := parseIRC(line)
+		switch command {
+		case "001":
+			// welcome, the nick was accepted
+			return nil
+		case "433":
+			// the nick is in use, try another
+			nick += "_"
+			c.text.PrintfLine("NICK %s", nick)
+		case "PING":
+			c.text.PrintfLine("PONG :%s", params)
+		case "ERROR":
+			return ErrIRCRegister
+		}
+	}
+}
+
+// read reads lines from the server until the connection
+// is closed, and answers pings to keep the connection
+// alive.
+func (c *ircConn) read() {
+	defer c.close()
+	for {
+		line, err := c.text.ReadLine()
+		if err != nil {
+			return
+		}
+		_, command, params := parseIRC(line)
+		switch command {
+		case "PING":
+			c.write("PONG :%s", params)
+		case "ERROR":
+			return
+		}
+	}
+}
+
+// join joins the channel, unless already joined.
+func (c *ircConn) join(channel string) error {
+	c.Lock()
+	joined := c.joined[channel]
+	c.joined[channel] = true
+	c.Unlock()
+	if joined {
+		return nil
+	}
+	return c.write("JOIN %s", channel)
+}
+
+// notice sends a notice to the channel.
+func (c *ircConn) notice(channel, msg string) error {
+	return c.write("NOTICE %s :%s", channel, msg)
+}
+
+// write sends a line to the server, and keeps the
+// connection open for reuse.
+func (c *ircConn) write(format string, args ...interface{}) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return errors.New("IRC connection is closed")
+	}
+	if c.idle != nil {
+		c.idle.Reset(ircIdleTimeout)
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(ircTimeout))
+	return c.text.PrintfLine(format, args...)
+}
+
+func (c *ircConn) isClosed() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.closed
+}
+
+// close quits and closes the connection, and removes
+// it from the pool.
+func (c *ircConn) close() {
+	c.Lock()
+	if c.closed {
+		c.Unlock()
+		return
+	}
+	if c.idle != nil {
+		c.idle.Stop()
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.text.PrintfLine("QUIT :Bye")
+	c.conn.Close()
+	c.closed = true
+	c.Unlock()
+
+	ircPool.Lock()
+	if ircPool.conns[c.key] == c {
+		delete(ircPool.conns, c.key)
+	}
+	ircPool.Unlock()
+}
+
+// parseIRC splits a line into the prefix, the command
+// and the parameters.
+func parseIRC(line string) (prefix, command, params string) {
+	if strings.HasPrefix(line, ":") {
+		parts := strings.SplitN(line[1:], " ", 2)
+		prefix = parts[0]
+		if len(parts) == 1 {
+			return prefix, "", ""
+		}
+		line = parts[1]
+	}
+	parts := strings.SplitN(line, " ", 2)
+	command = strings.ToUpper(parts[0])
+	if len(parts) == 2 {
+		params = strings.TrimPrefix(parts[1], ":")
+	}
+	return prefix, command, params
+}
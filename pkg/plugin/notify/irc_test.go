@@ -0,0 +1,250 @@
+package notify
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/pkg/model"
+)
+
+// ircServer is a minimal, in-process IRC server that
+// records the lines it receives.
+type ircServer struct {
+	listener net.Listener
+
+	// welcome is false if the server never
+	// accepts the nick.
+	welcome bool
+
+	sync.Mutex
+	conns []net.Conn
+	lines []string
+}
+
+func newIRCServer(t *testing.T, welcome bool) *ircServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &ircServer{listener: listener, welcome: welcome}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.Lock()
+			server.conns = append(server.conns, conn)
+			server.Unlock()
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *ircServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+
+	// ping the client before registration
+	text.PrintfLine("PING :irc.example.com")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		s.Lock()
+		s.lines = append(s.lines, line)
+		s.Unlock()
+
+		switch {
+		case strings.HasPrefix(line, "NICK drone") && line != "NICK drone_":
+			text.PrintfLine(":irc.example.com 433 * drone :Nickname is already in use")
+		case strings.HasPrefix(line, "USER") && s.welcome:
+			text.PrintfLine(":irc.example.com 001 drone_ :Welcome")
+		case strings.HasPrefix(line, "QUIT"):
+			return
+		}
+	}
+}
+
+// received returns the lines received by the server,
+// and the number of connections.
+func (s *ircServer) received() ([]string, int) {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string{}, s.lines...), len(s.conns)
+}
+
+// disconnect closes all client connections.
+func (s *ircServer) disconnect() {
+	s.Lock()
+	defer s.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *ircServer) Close() {
+	s.listener.Close()
+	s.disconnect()
+}
+
+// waitFor waits until the server received the line.
+func (s *ircServer) waitFor(line string) bool {
+	for i := 0; i < 100; i++ {
+		lines, _ := s.received()
+		for _, l := range lines {
+			if l == line {
+				return true
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestIRC(t *testing.T) {
+	server := newIRCServer(t, true)
+	defer server.Close()
+
+	irc := &IRC{
+		Server:   server.listener.Addr().String(),
+		Password: "secret",
+		Nick:     "drone",
+		NickServ: "identify",
+		Channel:  "#drone",
+		Channels: []string{"builds"},
+		Success:  true,
+	}
+	context := &Context{
+		Host:   "http://drone.example.com",
+		Repo:   &model.Repo{Slug: "github.com/drone/drone", Name: "drone"},
+		Commit: &model.Commit{Status: "Success", Hash: "4f4c45b1d8a0", Branch: "master", Author: "brad@drone.io"},
+	}
+
+	// the connection is reused for the second build
+	for i := 0; i < 2; i++ {
+		if err := irc.Send(context); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notice := "NOTICE #builds :Success drone (master), commit 4f4c45, author brad@drone.io: http://drone.example.com/github.com/drone/drone/commit/4f4c45b1d8a0"
+	if !server.waitFor(notice) {
+		lines, _ := server.received()
+		t.Fatalf("Expected notice %q, got %q", notice, lines)
+	}
+
+	lines, conns := server.received()
+	if conns != 1 {
+		t.Errorf("Expected one connection, got %d", conns)
+	}
+	want := []string{
+		"PONG :irc.example.com",
+		"PASS secret",
+		"NICK drone_",
+		"PRIVMSG NickServ :IDENTIFY identify",
+		"JOIN #drone",
+		"JOIN #builds",
+	}
+	for _, line := range want {
+		if count(lines, line) != 1 {
+			t.Errorf("Expected %q once, got %q", line, lines)
+		}
+	}
+	if count(lines, notice) != 2 {
+		t.Errorf("Expected a notice for each build, got %q", lines)
+	}
+
+	// a new connection is opened if the server
+	// closed the shared connection.
+	server.disconnect()
+	time.Sleep(50 * time.Millisecond)
+	if err := irc.Send(context); err != nil {
+		t.Fatal(err)
+	}
+	if _, conns := server.received(); conns != 2 {
+		t.Errorf("Expected a new connection, got %d", conns)
+	}
+}
+
+func TestIRCTimeout(t *testing.T) {
+	server := newIRCServer(t, false)
+	defer server.Close()
+
+	timeout := ircTimeout
+	ircTimeout = 100 * time.Millisecond
+	defer func() { ircTimeout = timeout }()
+
+	irc := &IRC{Server: server.listener.Addr().String(), Nick: "drone", Channel: "#drone", Failure: true}
+	context := &Context{
+		Repo:   &model.Repo{Slug: "github.com/drone/drone"},
+		Commit: &model.Commit{Status: "Failure"},
+	}
+	if err := irc.Send(context); err == nil {
+		t.Errorf("Expected an error when the server never accepts the nick")
+	}
+}
+
+func TestIRCConnectConcurrent(t *testing.T) {
+	slow := newIRCServer(t, false)
+	defer slow.Close()
+	server := newIRCServer(t, true)
+	defer server.Close()
+
+	timeout := ircTimeout
+	ircTimeout = 500 * time.Millisecond
+	defer func() { ircTimeout = timeout }()
+
+	context := &Context{
+		Repo:   &model.Repo{Slug: "github.com/drone/drone"},
+		Commit: &model.Commit{Status: "Failure", Hash: "4f4c45b1d8a0"},
+	}
+
+	// a server that never accepts the nick does
+	// not hold up the builds notifying another.
+	go (&IRC{Server: slow.listener.Addr().String(), Nick: "drone", Channel: "#drone", Failure: true}).Send(context)
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	irc := &IRC{Server: server.listener.Addr().String(), Nick: "drone", Channel: "#drone", Failure: true}
+	if err := irc.Send(context); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("Expected the notice to be sent while the slow server is dialed, took %s", elapsed)
+	}
+}
+
+func TestIRCSanitize(t *testing.T) {
+	var tests = []struct {
+		line string
+		want string
+	}{
+		{"Success drone", "Success drone"},
+		{"Success\r\x01drone\x00", "Successdrone"},
+		{"drone\rQUIT :bye", "droneQUIT :bye"},
+		{"\r", ""},
+	}
+	for _, test := range tests {
+		if got := ircSanitize(test.line); got != test.want {
+			t.Errorf("Expected %q sanitized as %q, got %q", test.line, test.want, got)
+		}
+	}
+}
+
+func count(lines []string, line string) int {
+	var n int
+	for _, l := range lines {
+		if l == line {
+			n++
+		}
+	}
+	return n
+}
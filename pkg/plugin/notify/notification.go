@@ -1,9 +1,45 @@
 package notify
 
 import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/drone/drone/pkg/model"
 )
 
+// httpTimeout is the maximum amount of time a notifier
+// spends on a request to a remote service, so that a slow
+// service cannot stall the build worker indefinitely.
+var httpTimeout = 30 * time.Second
+
+// httpClient is the HTTP client shared by the notifiers.
+// Each request uses a new connection, with a deadline
+// that covers connecting, sending the request and reading
+// the response.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		Dial:              dialTimeout,
+		DisableKeepAlives: true,
+	},
+}
+
+// dialTimeout connects to the address, and sets the
+// deadline of the connection to httpTimeout from now.
+func dialTimeout(network, addr string) (net.Conn, error) {
+	conn, err := net.DialTimeout(network, addr, httpTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(httpTimeout))
+	return conn, nil
+}
+
 // Context represents the context of an
 // in-progress build request.
 type Context struct {
@@ -18,6 +54,65 @@ type Context struct {
 
 	// Commit being built
 	Commit *model.Commit
+
+	// Build of the commit, including the output
+	// once the build is finished.
+	Build *model.Build
+
+	// PrevStatus is the status of the previous finished
+	// build of the branch, or empty if there is none.
+	PrevStatus string
+
+	// SaveNotification records the result of each
+	// notification sent for the build, and is nil if
+	// the results are not recorded.
+	SaveNotification func(*model.Notification) error
+
+	// SaveDelivery records the result of each webhook
+	// delivery attempt in the delivery log of the
+	// repository, and is nil if it is not recorded.
+	SaveDelivery func(*model.Delivery) error
+
+	// FindUser returns the Drone user with the given
+	// login, used to find the email address of authors
+	// that are identified by login, such as for pull
+	// requests. It is nil if users cannot be found.
+	FindUser func(login string) (*model.User, error)
+}
+
+// IsChanged returns true if the build finished with a
+// different status than the previous build of the branch.
+func (c *Context) IsChanged() bool {
+	return isFinished(c.Commit.Status) && c.Commit.Status != c.PrevStatus
+}
+
+// IsFixed returns true if the build passed, and the
+// previous build of the branch failed.
+func (c *Context) IsFixed() bool {
+	return c.Commit.Status == model.StatusSuccess && isFailed(c.PrevStatus)
+}
+
+// IsBroken returns true if the build failed, and the
+// previous build of the branch passed.
+func (c *Context) IsBroken() bool {
+	return isFailed(c.Commit.Status) && c.PrevStatus == model.StatusSuccess
+}
+
+// onChange is a helper function that returns true if the
+// build matches one of the status change conditions of a
+// notifier, which are on_change, on_fixed and on_broken.
+func onChange(context *Context, change, fixed, broken bool) bool {
+	return (change && context.IsChanged()) ||
+		(fixed && context.IsFixed()) ||
+		(broken && context.IsBroken())
+}
+
+func isFinished(status string) bool {
+	return status == model.StatusSuccess || isFailed(status)
+}
+
+func isFailed(status string) bool {
+	return status == model.StatusFailure || status == model.StatusError
 }
 
 type Sender interface {
@@ -32,28 +127,138 @@ type Notification struct {
 	Webhook *Webhook `yaml:"webhook,omitempty"`
 	Hipchat *Hipchat `yaml:"hipchat,omitempty"`
 	Irc     *IRC     `yaml:"irc,omitempty"`
+	Slack   *Slack   `yaml:"slack,omitempty"`
+}
+
+// Errors is returned by Send if one or more notifiers
+// failed, and maps the name of each failed notifier,
+// such as hipchat, to its error.
+type Errors map[string]error
+
+func (e Errors) Error() string {
+	var names []string
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var msgs []string
+	for _, name := range names {
+		msgs = append(msgs, name+": "+e[name].Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// notifier is implemented by each type of notification,
+// so that the result of a notification can be recorded,
+// and a failed notification can be sent again.
+type notifier interface {
+	Sender
+
+	// enabled returns true if the notifier is configured
+	// to notify about the status of the build.
+	enabled(context *Context) bool
+
+	// sendStatus sends the notification for the status
+	// of the build, whether or not it is enabled.
+	sendStatus(context *Context) error
+
+	// target describes where notifications are sent,
+	// such as the email recipients or the room.
+	target(context *Context) string
 }
 
+// Send sends each configured notification. A failed
+// notifier does not prevent the others from sending,
+// and its error is included in the returned Errors.
+// The result of each notification is recorded against
+// the build, if any.
 func (n *Notification) Send(context *Context) error {
-	// send email notifications
-	if n.Email != nil {
-		n.Email.Send(context)
+	errs := Errors{}
+	for _, sender := range n.senders() {
+		if !sender.enabled(context) {
+			continue
+		}
+		result := &model.Notification{Notifier: sender.name, Event: context.Commit.Status}
+		if err := sender.send(context, result); err != nil {
+			errs[sender.name] = err
+		}
+	}
+	if len(errs) != 0 {
+		return errs
 	}
+	return nil
+}
 
-	// send email notifications
-	if n.Webhook != nil {
-		n.Webhook.Send(context)
+// Retry sends the recorded notification again, with the
+// configuration of the notifier in the .drone.yml file,
+// and for the same build status, and records the result.
+func (n *Notification) Retry(result *model.Notification, context *Context) error {
+	for _, sender := range n.senders() {
+		if sender.name != result.Notifier {
+			continue
+		}
+
+		// the context and commit are copied, since the
+		// notification is sent for the status of the build
+		// at the time.
+		commit := *context.Commit
+		commit.Status = result.Event
+		retry := *context
+		retry.Commit = &commit
+		return sender.send(&retry, result)
 	}
+	return fmt.Errorf("No %s notification is configured", result.Notifier)
+}
 
-	// send email notifications
-	if n.Hipchat != nil {
-		n.Hipchat.Send(context)
+// namedSender is a configured notifier, with the name
+// used to record its results.
+type namedSender struct {
+	notifier
+	name string
+}
+
+// send sends the notification, and records the result
+// against the build, if any.
+func (s namedSender) send(context *Context, result *model.Notification) error {
+	err := s.sendStatus(context)
+	if context.Build == nil || context.SaveNotification == nil {
+		return err
 	}
 
-	// send irc notifications
-	if n.Irc != nil {
-		n.Irc.Send(context)
+	result.RepoID = context.Repo.ID
+	result.BuildID = context.Build.ID
+	result.Target = s.target(context)
+	result.Attempts++
+	result.Status = model.StatusSuccess
+	result.Error = ""
+	if err != nil {
+		result.Status = model.StatusFailure
+		result.Error = err.Error()
 	}
+	if serr := context.SaveNotification(result); serr != nil {
+		log.Printf("error saving %s notification: %s\n", s.name, serr)
+	}
+	return err
+}
 
-	return nil
+// senders returns the configured notifiers.
+func (n *Notification) senders() []namedSender {
+	var senders []namedSender
+	if n.Email != nil {
+		senders = append(senders, namedSender{n.Email, "email"})
+	}
+	if n.Webhook != nil {
+		senders = append(senders, namedSender{n.Webhook, "webhook"})
+	}
+	if n.Hipchat != nil {
+		senders = append(senders, namedSender{n.Hipchat, "hipchat"})
+	}
+	if n.Irc != nil {
+		senders = append(senders, namedSender{n.Irc, "irc"})
+	}
+	if n.Slack != nil {
+		senders = append(senders, namedSender{n.Slack, "slack"})
+	}
+	return senders
 }
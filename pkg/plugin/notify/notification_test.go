@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drone/drone/pkg/model"
+)
+
+func TestStatusChange(t *testing.T) {
+	var tests = []struct {
+		status  string
+		prev    string
+		changed bool
+		fixed   bool
+		broken  bool
+	}{
+		{"Started", "Success", false, false, false},
+		{"Success", "Success", false, false, false},
+		{"Failure", "Failure", false, false, false},
+		{"Success", "Failure", true, true, false},
+		{"Success", "Error", true, true, false},
+		{"Failure", "Success", true, false, true},
+		{"Error", "Success", true, false, true},
+		{"Failure", "Error", true, false, false},
+		// the first build of a branch
+		{"Success", "", true, false, false},
+		{"Failure", "", true, false, false},
+	}
+
+	for _, test := range tests {
+		context := &Context{Commit: &model.Commit{Status: test.status}, PrevStatus: test.prev}
+		if changed := context.IsChanged(); changed != test.changed {
+			t.Errorf("Expected changed %v for %s after %q, got %v", test.changed, test.status, test.prev, changed)
+		}
+		if fixed := context.IsFixed(); fixed != test.fixed {
+			t.Errorf("Expected fixed %v for %s after %q, got %v", test.fixed, test.status, test.prev, fixed)
+		}
+		if broken := context.IsBroken(); broken != test.broken {
+			t.Errorf("Expected broken %v for %s after %q, got %v", test.broken, test.status, test.prev, broken)
+		}
+	}
+}
+
+func TestNotificationErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var messages int
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		messages++
+	}))
+	defer ok.Close()
+
+	// the failed hipchat notifier does not prevent
+	// the slack notifier from sending.
+	notification := &Notification{
+		Hipchat: &Hipchat{Server: server.URL, Room: "drone", Failure: true},
+		Slack:   &Slack{URL: ok.URL, Failure: true},
+	}
+	context := &Context{
+		Repo:   &model.Repo{Name: "drone"},
+		Commit: &model.Commit{Status: "Failure", Hash: "4f4c45b1d8a0"},
+	}
+
+	err := notification.Send(context)
+	errs, isErrors := err.(Errors)
+	if !isErrors || len(errs) != 1 || errs["hipchat"] == nil {
+		t.Errorf("Expected the hipchat error, got %v", err)
+	}
+	if messages != 1 {
+		t.Errorf("Expected the slack message to be sent, got %d", messages)
+	}
+}
+
+func TestNotificationResults(t *testing.T) {
+	var results []*model.Notification
+	saveNotification := func(result *model.Notification) error {
+		copy := *result
+		results = append(results, &copy)
+		return nil
+	}
+
+	status := http.StatusUnauthorized
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	// the disabled slack notifier is not recorded
+	notification := &Notification{
+		Hipchat: &Hipchat{Server: server.URL, Room: "Drone Builds", Token: "3a2b1c", Failure: true},
+		Slack:   &Slack{URL: server.URL, Success: true},
+	}
+	context := &Context{
+		Repo:   &model.Repo{ID: 1, Name: "drone"},
+		Commit: &model.Commit{Status: "Failure", Hash: "4f4c45b1d8a0"},
+		Build:  &model.Build{ID: 2},
+
+		SaveNotification: saveNotification,
+	}
+	if err := notification.Send(context); err == nil {
+		t.Errorf("Expected the hipchat error")
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected one result, got %d", len(results))
+	}
+
+	result := results[0]
+	if result.RepoID != 1 || result.BuildID != 2 {
+		t.Errorf("Expected the result of the build, got repo %d build %d", result.RepoID, result.BuildID)
+	}
+	if result.Notifier != "hipchat" || result.Target != "Drone Builds" || result.Event != "Failure" {
+		t.Errorf("Expected the hipchat failure notification, got %+v", result)
+	}
+	if result.Status != model.StatusFailure || len(result.Error) == 0 || result.Attempts != 1 {
+		t.Errorf("Expected a failed attempt, got %+v", result)
+	}
+
+	// the notification is sent again with the configured
+	// notifier, for the recorded status, even if the
+	// notifier would not notify about the build now.
+	status = http.StatusNoContent
+	context.Commit.Status = "Success"
+	if err := notification.Retry(result, context); err != nil {
+		t.Fatal(err)
+	}
+	retried := results[1]
+	if retried.Status != model.StatusSuccess || len(retried.Error) != 0 || retried.Attempts != 2 {
+		t.Errorf("Expected a successful second attempt, got %+v", retried)
+	}
+	if retried.Event != "Failure" {
+		t.Errorf("Expected the event to be unchanged, got %s", retried.Event)
+	}
+	if context.Commit.Status != "Success" {
+		t.Errorf("Expected the commit to be unchanged, got %s", context.Commit.Status)
+	}
+
+	// the notification is not sent if the notifier was
+	// removed from the .drone.yml file.
+	notification.Hipchat = nil
+	if err := notification.Retry(result, context); err == nil {
+		t.Errorf("Expected an error retrying the removed hipchat notifier")
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected no further results, got %d", len(results))
+	}
+}
@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	slackStartedMessage = "Building %s (%s), commit %s, author %s"
+	slackSuccessMessage = "Success %s (%s), commit %s, author %s"
+	slackFailureMessage = "Failed %s (%s), commit %s, author %s"
+)
+
+// Slack posts build notifications to a Slack
+// incoming webhook.
+type Slack struct {
+	URL      string `yaml:"webhook_url,omitempty"`
+	Channel  string `yaml:"channel,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	Started  bool   `yaml:"on_started,omitempty"`
+	Success  bool   `yaml:"on_success,omitempty"`
+	Failure  bool   `yaml:"on_failure,omitempty"`
+	Change   bool   `yaml:"on_change,omitempty"`
+	Fixed    bool   `yaml:"on_fixed,omitempty"`
+	Broken   bool   `yaml:"on_broken,omitempty"`
+	Template string `yaml:"template,omitempty"`
+}
+
+func (s *Slack) Send(context *Context) error {
+	if !s.enabled(context) {
+		return nil
+	}
+	return s.sendStatus(context)
+}
+
+func (s *Slack) enabled(context *Context) bool {
+	switch context.Commit.Status {
+	case "Started":
+		return s.Started
+	case "Success":
+		return s.Success || onChange(context, s.Change, s.Fixed, s.Broken)
+	case "Failure":
+		return s.Failure || onChange(context, s.Change, s.Fixed, s.Broken)
+	}
+	return false
+}
+
+func (s *Slack) sendStatus(context *Context) error {
+	switch context.Commit.Status {
+	case "Started":
+		return s.send(context, slackStartedMessage, "warning")
+	case "Success":
+		return s.send(context, slackSuccessMessage, "good")
+	}
+	return s.send(context, slackFailureMessage, "danger")
+}
+
+// target returns the channel, or the default
+// channel of the webhook.
+func (s *Slack) target(context *Context) string {
+	if len(s.Channel) == 0 {
+		return "default channel"
+	}
+	return s.Channel
+}
+
+// slackMessage is the payload of a Slack incoming webhook.
+type slackMessage struct {
+	Channel     string             `json:"channel,omitempty"`
+	Username    string             `json:"username,omitempty"`
+	Attachments []*slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Fallback  string        `json:"fallback"`
+	Color     string        `json:"color"`
+	Title     string        `json:"title"`
+	TitleLink string        `json:"title_link"`
+	Text      string        `json:"text,omitempty"`
+	Fields    []*slackField `json:"fields"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// helper function to post the message, with an attachment
+// colored by the status of the build, to the webhook.
+func (s *Slack) send(context *Context, format, color string) error {
+	repo, commit := context.Repo, context.Commit
+	msg := fmt.Sprintf(format, repo.Slug, commit.Branch, commit.HashShort(), commit.Author)
+
+	attachment := &slackAttachment{
+		Fallback:  msg,
+		Color:     color,
+		Title:     msg,
+		TitleLink: link(context),
+		Fields: []*slackField{
+			{"Repository", repo.Slug, true},
+			{"Branch", commit.Branch, true},
+			{"Commit", commit.HashShort(), true},
+			{"Author", commit.Author, true},
+		},
+	}
+
+	// the duration is only known once
+	// the build is finished.
+	if commit.Status != "Started" {
+		attachment.Fields = append(attachment.Fields, &slackField{"Duration", duration(commit), true})
+	}
+
+	// the user-defined template is rendered as the
+	// text of the attachment.
+	if hasTemplate(s.Template, context) {
+		text, err := render(s.Template, context)
+		if err != nil {
+			return err
+		}
+		attachment.Text = text
+		attachment.Fallback = text
+	}
+
+	payload, err := json.Marshal(&slackMessage{
+		Channel:     s.Channel,
+		Username:    s.Username,
+		Attachments: []*slackAttachment{attachment},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Post(s.URL, "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Slack responded with %s: %s", resp.Status, body)
+	}
+	return nil
+}
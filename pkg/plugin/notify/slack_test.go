@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/pkg/model"
+)
+
+func TestSlack(t *testing.T) {
+	var messages []*slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := slackMessage{}
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Error(err)
+		}
+		messages = append(messages, &msg)
+	}))
+	defer server.Close()
+
+	slack := &Slack{URL: server.URL, Channel: "#builds", Username: "drone", Success: true, Failure: true}
+	context := &Context{
+		Host: "http://drone.example.com",
+		Repo: &model.Repo{Slug: "github.com/drone/drone"},
+		Commit: &model.Commit{
+			Status:   "Started",
+			Hash:     "4f4c45b1d8a0",
+			Branch:   "master",
+			Author:   "brad@drone.io",
+			Duration: int64(90 * time.Second),
+		},
+	}
+
+	// started notifications are disabled
+	if err := slack.Send(context); err != nil {
+		t.Error(err)
+	}
+	if len(messages) != 0 {
+		t.Fatalf("Expected no message for a started build, got %d", len(messages))
+	}
+
+	var tests = []struct {
+		status string
+		color  string
+	}{
+		{"Success", "good"},
+		{"Failure", "danger"},
+	}
+
+	for _, test := range tests {
+		messages = nil
+		context.Commit.Status = test.status
+		if err := slack.Send(context); err != nil {
+			t.Error(err)
+		}
+		if len(messages) != 1 {
+			t.Fatalf("Expected one message for status %s, got %d", test.status, len(messages))
+		}
+
+		msg := messages[0]
+		if msg.Channel != "#builds" || msg.Username != "drone" {
+			t.Errorf("Expected channel #builds and username drone, got %s and %s", msg.Channel, msg.Username)
+		}
+		attachment := msg.Attachments[0]
+		if attachment.Color != test.color {
+			t.Errorf("Expected color %s for status %s, got %s", test.color, test.status, attachment.Color)
+		}
+		if link := "http://drone.example.com/github.com/drone/drone/commit/4f4c45b1d8a0"; attachment.TitleLink != link {
+			t.Errorf("Expected link %s, got %s", link, attachment.TitleLink)
+		}
+
+		fields := map[string]string{}
+		for _, field := range attachment.Fields {
+			fields[field.Title] = field.Value
+		}
+		want := map[string]string{
+			"Repository": "github.com/drone/drone",
+			"Branch":     "master",
+			"Commit":     "4f4c45",
+			"Author":     "brad@drone.io",
+			"Duration":   "1m30s",
+		}
+		for title, value := range want {
+			if fields[title] != value {
+				t.Errorf("Expected %s %s, got %s", title, value, fields[title])
+			}
+		}
+	}
+}
+
+func TestSlackError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no_text", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	slack := &Slack{URL: server.URL, Failure: true}
+	context := &Context{
+		Repo:   &model.Repo{Slug: "github.com/drone/drone"},
+		Commit: &model.Commit{Status: "Failure"},
+	}
+	if err := slack.Send(context); err == nil {
+		t.Errorf("Expected an error when Slack rejects the message")
+	}
+}
+
+func TestSlackOnChange(t *testing.T) {
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+	}))
+	defer server.Close()
+
+	slack := &Slack{URL: server.URL, Fixed: true, Broken: true}
+	context := &Context{
+		Repo:       &model.Repo{Slug: "github.com/drone/drone"},
+		Commit:     &model.Commit{Status: "Failure"},
+		PrevStatus: "Failure",
+	}
+
+	// a branch that is still failing
+	if err := slack.Send(context); err != nil {
+		t.Error(err)
+	}
+	if sent != 0 {
+		t.Errorf("Expected no message while the branch is still failing, got %d", sent)
+	}
+
+	// a branch that is fixed
+	context.Commit.Status = "Success"
+	if err := slack.Send(context); err != nil {
+		t.Error(err)
+	}
+	if sent != 1 {
+		t.Errorf("Expected a message when the branch is fixed, got %d", sent)
+	}
+}
+
+func TestSlackTimeout(t *testing.T) {
+	httpTimeout = 50 * time.Millisecond
+	defer func() { httpTimeout = 30 * time.Second }()
+
+	// the service never responds
+	done := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	slack := &Slack{URL: server.URL, Failure: true}
+	context := &Context{
+		Repo:   &model.Repo{Slug: "github.com/drone/drone"},
+		Commit: &model.Commit{Status: "Failure", Hash: "4f4c45b1d8a0"},
+	}
+	if err := slack.Send(context); err == nil {
+		t.Errorf("Expected error when Slack does not respond")
+	}
+}
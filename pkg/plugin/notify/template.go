@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/drone/drone/pkg/model"
+)
+
+// logTailLines is the number of lines at the end of
+// the build output that are available to templates.
+const logTailLines = 20
+
+// Message is the data that user-defined notification
+// templates are rendered with, for example:
+//
+//	{{.Repo.Slug}} {{.Commit.Branch}} {{.Commit.Status}} in {{.Duration}}
+//	{{.Link}}
+//
+// It is decoupled from the database models, like the
+// webhook payload, so that templates cannot read the
+// credentials and keys of the repository.
+type Message struct {
+	// Repository being built.
+	Repo *MessageRepo
+
+	// Commit being built, including the branch,
+	// author, message and pull request number.
+	Commit *MessageCommit
+
+	// Build of the commit, or nil if unknown.
+	Build *MessageBuild
+
+	// Duration of the finished build, such as 1m30s.
+	Duration string
+
+	// Link to the build page.
+	Link string
+
+	// LogTail is the end of the build output.
+	LogTail string
+}
+
+// MessageRepo is the repository of a Message.
+type MessageRepo struct {
+	Slug  string
+	Host  string
+	Owner string
+	Name  string
+	URL   string
+}
+
+// MessageCommit is the commit of a Message.
+type MessageCommit struct {
+	Status      string
+	Hash        string
+	Branch      string
+	PullRequest string
+	Author      string
+	Message     string
+	Timestamp   string
+}
+
+// HashShort returns the short commit hash.
+func (c *MessageCommit) HashShort() string {
+	if len(c.Hash) > 6 {
+		return c.Hash[:6]
+	}
+	return c.Hash
+}
+
+// MessageBuild is the build of a Message.
+type MessageBuild struct {
+	Slug   string
+	Status string
+}
+
+// newMessage creates the template data of the build.
+func newMessage(context *Context) *Message {
+	repo, commit := context.Repo, context.Commit
+	msg := &Message{
+		Repo: &MessageRepo{
+			Slug:  repo.Slug,
+			Host:  repo.Host,
+			Owner: repo.Owner,
+			Name:  repo.Name,
+			URL:   repo.URL,
+		},
+		Commit: &MessageCommit{
+			Status:      commit.Status,
+			Hash:        commit.Hash,
+			Branch:      commit.Branch,
+			PullRequest: commit.PullRequest,
+			Author:      commit.Author,
+			Message:     commit.Message,
+			Timestamp:   commit.Timestamp,
+		},
+		Link: link(context),
+	}
+	if commit.Status != model.StatusStarted {
+		msg.Duration = duration(commit)
+	}
+	if build := context.Build; build != nil {
+		msg.Build = &MessageBuild{Slug: build.Slug, Status: build.Status}
+		msg.LogTail = tail(build.Stdout, logTailLines)
+	}
+	return msg
+}
+
+// hasTemplate returns true if the user-defined template is
+// used instead of the default message. Templates are ignored
+// for pull requests, since the contributor controls them.
+func hasTemplate(text string, context *Context) bool {
+	return len(text) != 0 && len(context.Commit.PullRequest) == 0
+}
+
+// render executes the user-defined text template
+// with the data of the build.
+func render(text string, context *Context) (string, error) {
+	t, err := template.New("notify").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, newMessage(context)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderHTML executes the user-defined template with the
+// data of the build, escaping the data for HTML messages.
+func renderHTML(text string, context *Context) (string, error) {
+	t, err := htmltemplate.New("notify").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, newMessage(context)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// link returns the URL of the build page.
+func link(context *Context) string {
+	return context.Host + "/" + context.Repo.Slug + "/commit/" + context.Commit.Hash
+}
+
+// duration returns the duration of the
+// build, rounded to the second.
+func duration(commit *model.Commit) string {
+	return (time.Duration(commit.Duration) / time.Second * time.Second).String()
+}
+
+// tail returns the last n lines of the output.
+func tail(output string, n int) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/pkg/model"
+)
+
+func TestRender(t *testing.T) {
+	var output []string
+	for i := 1; i <= 30; i++ {
+		output = append(output, fmt.Sprintf("line %d", i))
+	}
+
+	context := &Context{
+		Host: "http://drone.example.com",
+		Repo: &model.Repo{Slug: "github.com/drone/drone", PrivateKey: "private key", Params: map[string]string{"token": "secret"}},
+		Commit: &model.Commit{
+			Status:      "Failure",
+			Hash:        "4f4c45b1d8a0",
+			Branch:      "master",
+			PullRequest: "42",
+			Duration:    int64(90 * time.Second),
+		},
+		Build: &model.Build{Stdout: strings.Join(output, "\n") + "\n"},
+	}
+
+	text, err := render("{{.Repo.Slug}} #{{.Commit.PullRequest}} {{.Commit.Status}} in {{.Duration}} {{.Link}}", context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "github.com/drone/drone #42 Failure in 1m30s http://drone.example.com/github.com/drone/drone/commit/4f4c45b1d8a0"
+	if text != want {
+		t.Errorf("Expected %q, got %q", want, text)
+	}
+
+	// only the end of the output is available
+	text, err = render("{{.LogTail}}", context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(text, "\n"); len(lines) != logTailLines || lines[0] != "line 11" || lines[19] != "line 30" {
+		t.Errorf("Expected the last %d lines of output, got %q", logTailLines, text)
+	}
+
+	// html templates escape the build data
+	context.Commit.Message = "<script>"
+	text, err = renderHTML("<p>{{.Commit.Message}}</p>", context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "<p>&lt;script&gt;</p>" {
+		t.Errorf("Expected the commit message to be escaped, got %q", text)
+	}
+
+	// the credentials of the repository are not available
+	if _, err := render("{{.Repo.PrivateKey}}", context); err == nil {
+		t.Errorf("Expected an error reading the private key")
+	}
+	if _, err := render("{{.Repo.Params}}", context); err == nil {
+		t.Errorf("Expected an error reading the private parameters")
+	}
+
+	if _, err := render("{{.Unknown", context); err == nil {
+		t.Errorf("Expected an error for an invalid template")
+	}
+}
+
+func TestHasTemplate(t *testing.T) {
+	context := &Context{Commit: &model.Commit{}}
+	if !hasTemplate("{{.Link}}", context) {
+		t.Errorf("Expected the template to be used")
+	}
+	if hasTemplate("", context) {
+		t.Errorf("Expected the default message without a template")
+	}
+
+	// the template of a pull request is ignored
+	context.Commit.PullRequest = "42"
+	if hasTemplate("{{.Link}}", context) {
+		t.Errorf("Expected the template to be ignored for a pull request")
+	}
+}
@@ -2,58 +2,276 @@ package notify
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/drone/drone/pkg/model"
 )
 
+// webhookVersion is the version of the payload format, which
+// is incremented when fields are changed or removed.
+const webhookVersion = 1
+
+// webhookAttempts is the number of times a
+// delivery is attempted before giving up.
+const webhookAttempts = 3
+
+// webhookBackoff is the delay before the first retry,
+// and is doubled before each subsequent retry.
+var webhookBackoff = 5 * time.Second
+
+// webhookDeliveries tracks the deliveries that are in
+// progress in the background.
+var webhookDeliveries sync.WaitGroup
+
 type Webhook struct {
 	URL     []string `yaml:"urls,omitempty"`
+	Started bool     `yaml:"on_started,omitempty"`
 	Success bool     `yaml:"on_success,omitempty"`
 	Failure bool     `yaml:"on_failure,omitempty"`
+	Change  bool     `yaml:"on_change,omitempty"`
+	Fixed   bool     `yaml:"on_fixed,omitempty"`
+	Broken  bool     `yaml:"on_broken,omitempty"`
+
+	// Secret is used to sign the payload, so that the
+	// receiver can verify it was sent by Drone.
+	Secret string `yaml:"secret,omitempty"`
+
+	// Headers are added to every request, for example
+	// to authenticate with the receiving service.
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Template is an optional text/template used to
+	// render the JSON payload instead of the default.
+	Template string `yaml:"template,omitempty"`
 }
 
 func (w *Webhook) Send(context *Context) error {
-	switch {
-	case context.Commit.Status == "Success" && w.Success:
-		return w.send(context)
-	case context.Commit.Status == "Failure" && w.Failure:
-		return w.send(context)
+	if !w.enabled(context) {
+		return nil
 	}
+	return w.sendStatus(context)
+}
 
-	return nil
+func (w *Webhook) enabled(context *Context) bool {
+	switch context.Commit.Status {
+	case "Started":
+		return w.Started
+	case "Success":
+		return w.Success || onChange(context, w.Change, w.Fixed, w.Broken)
+	case "Failure":
+		return w.Failure || onChange(context, w.Change, w.Fixed, w.Broken)
+	}
+	return false
+}
+
+func (w *Webhook) sendStatus(context *Context) error {
+	return w.send(context)
+}
+
+func (w *Webhook) target(context *Context) string {
+	return strings.Join(w.URL, ", ")
+}
+
+// webhookPayload is the default payload posted to the
+// webhook. It is decoupled from the database models so
+// that credentials and keys are never sent, and so that
+// the format only changes with the version.
+type webhookPayload struct {
+	Version int            `json:"version"`
+	Event   string         `json:"event"`
+	Link    string         `json:"link"`
+	Owner   *webhookOwner  `json:"owner,omitempty"`
+	Repo    *webhookRepo   `json:"repository"`
+	Commit  *webhookCommit `json:"commit"`
+}
+
+type webhookOwner struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Gravatar string `json:"gravatar"`
+}
+
+type webhookRepo struct {
+	Slug    string `json:"slug"`
+	Host    string `json:"host"`
+	Owner   string `json:"owner"`
+	Name    string `json:"name"`
+	Private bool   `json:"private"`
+	SCM     string `json:"scm"`
+	URL     string `json:"url"`
+}
+
+type webhookCommit struct {
+	Status      string    `json:"status"`
+	Started     time.Time `json:"started"`
+	Finished    time.Time `json:"finished"`
+	Duration    int64     `json:"duration"`
+	Hash        string    `json:"hash"`
+	Branch      string    `json:"branch"`
+	PullRequest string    `json:"pull_request"`
+	Author      string    `json:"author"`
+	Gravatar    string    `json:"gravatar"`
+	Timestamp   string    `json:"timestamp"`
+	Message     string    `json:"message"`
+}
+
+// newPayload creates the default payload of the build.
+func newPayload(context *Context) *webhookPayload {
+	repo, commit := context.Repo, context.Commit
+	payload := &webhookPayload{
+		Version: webhookVersion,
+		Event:   strings.ToLower(commit.Status),
+		Link:    link(context),
+		Repo: &webhookRepo{
+			Slug:    repo.Slug,
+			Host:    repo.Host,
+			Owner:   repo.Owner,
+			Name:    repo.Name,
+			Private: repo.Private,
+			SCM:     repo.SCM,
+			URL:     repo.URL,
+		},
+		Commit: &webhookCommit{
+			Status:      commit.Status,
+			Started:     commit.Started,
+			Finished:    commit.Finished,
+			Duration:    commit.Duration,
+			Hash:        commit.Hash,
+			Branch:      commit.Branch,
+			PullRequest: commit.PullRequest,
+			Author:      commit.Author,
+			Gravatar:    commit.Gravatar,
+			Timestamp:   commit.Timestamp,
+			Message:     commit.Message,
+		},
+	}
+	if user := context.User; user != nil {
+		payload.Owner = &webhookOwner{user.Name, user.Email, user.Gravatar}
+	}
+	return payload
 }
 
 // helper function to send HTTP requests
 func (w *Webhook) send(context *Context) error {
-	// data will get posted in this format
-	data := struct {
-		Owner  *model.User   `json:"owner"`
-		Repo   *model.Repo   `json:"repository"`
-		Commit *model.Commit `json:"commit"`
-	}{context.User, context.Repo, context.Commit}
-
 	// data json encoded
-	payload, err := json.Marshal(data)
+	payload, err := json.Marshal(newPayload(context))
 	if err != nil {
 		return err
 	}
 
-	// loop through and email recipients
+	// the user-defined template replaces the payload
+	if hasTemplate(w.Template, context) {
+		body, err := render(w.Template, context)
+		if err != nil {
+			return err
+		}
+		payload = []byte(body)
+	}
+
+	// deliver to all urls in the background, since failed
+	// deliveries are retried with a backoff, which would
+	// otherwise hold up the build.
+	delivery := model.Delivery{
+		RepoID:   context.Repo.ID,
+		CommitID: context.Commit.ID,
+		Event:    strings.ToLower(context.Commit.Status),
+	}
 	for _, url := range w.URL {
-		go sendJson(url, payload)
+		webhookDeliveries.Add(1)
+		go func(url string) {
+			defer webhookDeliveries.Done()
+			w.deliver(context.SaveDelivery, delivery, url, payload)
+		}(url)
 	}
 	return nil
 }
 
-// helper fuction to sent HTTP Post requests
-// with JSON data as the payload.
-func sendJson(url string, payload []byte) {
-	buf := bytes.NewBuffer(payload)
-	resp, err := http.Post(url, "application/json", buf)
+// deliver posts the payload to the url, retrying with an
+// exponential backoff until the webhook responds with a 2xx
+// status code. Each attempt is recorded in the delivery log,
+// if save is not nil.
+func (w *Webhook) deliver(save func(*model.Delivery) error, base model.Delivery, url string, payload []byte) {
+	backoff := webhookBackoff
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		delivery := base
+		delivery.URL = url
+		delivery.Attempt = attempt
+
+		start := time.Now()
+		status, err := w.post(url, delivery.Event, payload)
+		delivery.Status = status
+		delivery.Duration = int64(time.Since(start) / time.Millisecond)
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if save != nil {
+			save(&delivery)
+		}
+
+		if err == nil {
+			return
+		}
+		if attempt == webhookAttempts {
+			log.Printf("error delivering %s webhook to %s: %s\n", delivery.Event, url, err)
+		}
+	}
+}
+
+// post sends a single HTTP Post request with the payload, and
+// returns the status code of the response. An error is returned
+// if the request failed, or the status code is not 2xx.
+func (w *Webhook) post(url, event string, payload []byte) (int, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
 	if err != nil {
-		return
+		return 0, err
+	}
+
+	// user-defined headers are set first, so they can
+	// override the content type of a templated payload,
+	// but not the headers set by Drone.
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.Headers {
+		req.Header.Set(key, value)
 	}
-	resp.Body.Close()
+	req.Header.Set("User-Agent", "Drone")
+	req.Header.Set("X-Drone-Event", event)
+	if len(w.Secret) != 0 {
+		req.Header.Set("X-Drone-Signature", "sha256="+sign(w.Secret, payload))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("Webhook responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// sign returns the hex encoded HMAC-SHA256
+// signature of the payload.
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
 }
@@ -87,10 +87,13 @@ func (s *S3) Write(f *buildfile.Buildfile) {
 		s.Target = s.Target[1:]
 	}
 
+	source := buildfile.Quote(s.Source)
+	target := buildfile.Quote(fmt.Sprintf("s3://%s/%s", s.Bucket, s.Target))
+
 	switch s.Recursive {
 	case true:
-		f.WriteCmd(fmt.Sprintf(`aws s3 cp %s s3://%s/%s --recursive --acl %s --region %s`, s.Source, s.Bucket, s.Target, s.Access, s.Region))
+		f.WriteCmd(fmt.Sprintf(`aws s3 cp %s %s --recursive --acl %s --region %s`, source, target, buildfile.Quote(s.Access), buildfile.Quote(s.Region)))
 	case false:
-		f.WriteCmd(fmt.Sprintf(`aws s3 cp %s s3://%s/%s --acl %s --region %s`, s.Source, s.Bucket, s.Target, s.Access, s.Region))
+		f.WriteCmd(fmt.Sprintf(`aws s3 cp %s %s --acl %s --region %s`, source, target, buildfile.Quote(s.Access), buildfile.Quote(s.Region)))
 	}
 }
@@ -0,0 +1,264 @@
+package queue
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/drone/drone/pkg/agent"
+	"github.com/drone/drone/pkg/build"
+	"github.com/drone/drone/pkg/build/docker"
+	"github.com/drone/drone/pkg/build/repo"
+	"github.com/drone/drone/pkg/build/script"
+)
+
+var (
+	// Returned if the agent did not pull the build,
+	// for example because it is no longer running.
+	ErrAgentUnavailable = errors.New("Build agent did not accept the build")
+
+	// Returned if the agent did not report the result
+	// of the build before the build timed out.
+	ErrAgentTimeout = errors.New("Build agent did not report the result of the build")
+
+	// returned if the agent did not acknowledge the
+	// build within the lease.
+	errLeaseExpired = errors.New("Build agent did not acknowledge the build")
+)
+
+// agentLease is the maximum amount of time an agent has to
+// acknowledge a build after it is handed to the agent, before
+// the build is offered again, for example because the response
+// to the agent's poll was lost.
+var agentLease = 10 * time.Second
+
+// agentLeases is the number of times a build is offered
+// to an agent before the agent is considered unavailable.
+const agentLeases = 3
+
+// Agents schedules builds onto remote agents, which pull
+// builds from the server and run them on their own Docker
+// host. Each agent is added to the pool the first time it
+// polls for a build.
+type Agents struct {
+	pool    *Pool
+	timeout time.Duration
+
+	sync.Mutex
+
+	// runners assign builds to each
+	// agent, indexed by agent name.
+	runners map[string]*agentRunner
+
+	// jobs that are running on an
+	// agent, indexed by job id.
+	jobs map[string]*job
+}
+
+// job is a build that is assigned to an agent,
+// and is waiting for the output and result.
+type job struct {
+	*agent.Job
+
+	// out is written with the build output
+	// streamed by the agent.
+	out io.Writer
+
+	result chan *agent.Result
+
+	// acked is closed when the agent acknowledges
+	// the job, or starts streaming the output.
+	acked chan bool
+	once  sync.Once
+}
+
+// ack marks the job as acknowledged by the agent.
+func (j *job) ack() {
+	j.once.Do(func() { close(j.acked) })
+}
+
+// NewAgents creates Agents that add capacity to the pool.
+// The timeout is the maximum amount of time a build is
+// allowed to run on an agent.
+func NewAgents(pool *Pool, timeout time.Duration) *Agents {
+	return &Agents{
+		pool:    pool,
+		timeout: timeout,
+		runners: map[string]*agentRunner{},
+		jobs:    map[string]*job{},
+	}
+}
+
+// Poll registers the agent with the given concurrency and
+// labels, and waits for a build to be assigned to it. It
+// returns nil if no build is assigned before the poll times
+// out, or the closed channel receives because the agent
+// disconnected.
+func (a *Agents) Poll(name string, concurrency int, labels []string, closed <-chan bool) *agent.Job {
+	a.Lock()
+	runner, ok := a.runners[name]
+	if !ok {
+		runner = &agentRunner{
+			agents: a,
+			jobs:   make(chan *job),
+		}
+		runner.host = &Host{
+			Address: "agent://" + name,
+			runner:  runner,
+		}
+		a.runners[name] = runner
+	}
+	a.Unlock()
+
+	a.pool.seen(runner.host, concurrency, labels)
+
+	select {
+	case job := <-runner.jobs:
+		return job.Job
+	case <-closed:
+		return nil
+	case <-time.After(agent.PollTimeout):
+		return nil
+	}
+}
+
+// Ack acknowledges that the agent received the build.
+// Builds that are not acknowledged within the lease are
+// offered again, as a new job.
+func (a *Agents) Ack(id string) error {
+	job := a.get(id)
+	if job == nil {
+		return agent.ErrJobNotFound
+	}
+	job.ack()
+	return nil
+}
+
+// Output copies the build output streamed by the agent
+// to the build's output, until the agent closes the stream.
+// Streaming the output also acknowledges the build.
+func (a *Agents) Output(id string, r io.Reader) error {
+	job := a.get(id)
+	if job == nil {
+		return agent.ErrJobNotFound
+	}
+	job.ack()
+	_, err := io.Copy(job.out, r)
+	return err
+}
+
+// Done reports the result of the build.
+func (a *Agents) Done(id string, result *agent.Result) error {
+	job := a.get(id)
+	if job == nil {
+		return agent.ErrJobNotFound
+	}
+
+	// the result channel is buffered, and only the
+	// first result reported for the job is used.
+	job.ack()
+	select {
+	case job.result <- result:
+	default:
+	}
+	return nil
+}
+
+// get returns the running job with the given
+// id, or nil if the job does not exist.
+func (a *Agents) get(id string) *job {
+	a.Lock()
+	defer a.Unlock()
+	return a.jobs[id]
+}
+
+// agentRunner is the BuildRunner of an agent's host in the
+// pool, which hands the build to the agent when it polls.
+type agentRunner struct {
+	agents *Agents
+	host   *Host
+
+	// jobs are received by the agent when it polls
+	// for a build. The channel is unbuffered, so a
+	// build is only assigned to an agent that polls.
+	jobs chan *job
+}
+
+func (r *agentRunner) Run(dockerClient *docker.Client, buildScript *script.Build, repo *repo.Repo, key []byte, limits build.Limits, buildOutput io.Writer) (bool, error) {
+	// the build is offered to the agent again, as a new job,
+	// if the agent does not acknowledge it within the lease.
+	// The agent is unable to report the output or result of
+	// the expired job, so the build never runs twice.
+	for i := 0; i < agentLeases; i++ {
+		job := &job{
+			Job: &agent.Job{
+				ID:      createJobID(),
+				Script:  buildScript,
+				Repo:    repo,
+				Key:     key,
+				Limits:  limits,
+				Timeout: r.agents.timeout,
+			},
+			out:    buildOutput,
+			result: make(chan *agent.Result, 1),
+			acked:  make(chan bool),
+		}
+
+		failed, err := r.run(job)
+		if err != errLeaseExpired {
+			return failed, err
+		}
+	}
+	return true, ErrAgentUnavailable
+}
+
+// run hands the job to the agent, and waits for the
+// agent to acknowledge the job and report the result.
+func (r *agentRunner) run(job *job) (bool, error) {
+	a := r.agents
+	a.Lock()
+	a.jobs[job.ID] = job
+	a.Unlock()
+
+	defer func() {
+		a.Lock()
+		delete(a.jobs, job.ID)
+		a.Unlock()
+	}()
+
+	// the agent polls again as soon as a build slot is
+	// free, so the build is accepted quickly unless the
+	// agent is no longer running.
+	select {
+	case r.jobs <- job:
+	case <-time.After(2 * agent.PollTimeout):
+		return true, ErrAgentUnavailable
+	}
+
+	select {
+	case <-job.acked:
+	case <-time.After(agentLease):
+		return true, errLeaseExpired
+	}
+
+	select {
+	case result := <-job.result:
+		if len(result.Error) != 0 {
+			return true, errors.New(result.Error)
+		}
+		return result.State == nil || result.State.ExitCode != 0, nil
+	case <-time.After(r.agents.timeout + agent.PollTimeout):
+		return true, ErrAgentTimeout
+	}
+}
+
+// createJobID is a helper function that will
+// create a random, unique job identifier.
+func createJobID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
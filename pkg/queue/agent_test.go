@@ -0,0 +1,133 @@
+package queue
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/pkg/agent"
+	"github.com/drone/drone/pkg/build"
+	"github.com/drone/drone/pkg/build/repo"
+	"github.com/drone/drone/pkg/build/script"
+)
+
+// acquireAgent adds a build to a queue on the pool, and
+// returns the host it is started on once an agent polls.
+func acquireAgent(t *testing.T, pool *Pool) *Host {
+	_, reset := stubPending()
+	defer reset()
+
+	q, started := testQueue(pool)
+	go q.listen()
+	q.Add(testTask("1"))
+
+	select {
+	case host := <-started:
+		return host
+	case <-time.After(time.Second):
+		t.Fatalf("Expected build to start once the agent polled")
+	}
+	return nil
+}
+
+func TestAgents(t *testing.T) {
+	pool := NewPool(nil, 1, nil)
+	pool.local.Healthy = false
+	agents := NewAgents(pool, 0)
+
+	// the agent polls for a build, which adds
+	// the agent to the pool.
+	polled := make(chan *agent.Job)
+	go func() {
+		polled <- agents.Poll("agent1", 2, nil, nil)
+	}()
+
+	host := acquireAgent(t, pool)
+	if host.Address != "agent://agent1" {
+		t.Fatalf("Expected build on agent://agent1, got %s", host.Address)
+	}
+
+	type result struct {
+		failed bool
+		err    error
+	}
+	var out bytes.Buffer
+	finished := make(chan result)
+	go func() {
+		failed, err := host.runner.Run(nil, &script.Build{Image: "go1.2"}, &repo.Repo{Name: "github.com/drone/drone"}, nil, build.Limits{Memory: 1 << 30}, &out)
+		finished <- result{failed, err}
+	}()
+
+	job := <-polled
+	if job == nil || job.Repo.Name != "github.com/drone/drone" {
+		t.Fatalf("Expected agent to receive the build, got %v", job)
+	}
+	if job.Limits.Memory != 1<<30 {
+		t.Errorf("Expected agent to receive the memory limit, got %d", job.Limits.Memory)
+	}
+
+	if err := agents.Output(job.ID, bytes.NewBufferString("PASS\n")); err != nil {
+		t.Error(err)
+	}
+	if err := agents.Done(job.ID, &agent.Result{State: &build.BuildState{ExitCode: 0}}); err != nil {
+		t.Error(err)
+	}
+
+	res := <-finished
+	if res.failed || res.err != nil {
+		t.Errorf("Expected build to pass, got %v %v", res.failed, res.err)
+	}
+	if out.String() != "PASS\n" {
+		t.Errorf("Expected build output from the agent, got %q", out.String())
+	}
+
+	// the job is removed once the build finished
+	if err := agents.Done(job.ID, &agent.Result{}); err != agent.ErrJobNotFound {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestAgentsLease(t *testing.T) {
+	agentLease = 50 * time.Millisecond
+	defer func() { agentLease = 10 * time.Second }()
+
+	pool := NewPool(nil, 1, nil)
+	pool.local.Healthy = false
+	agents := NewAgents(pool, 0)
+
+	polled := make(chan *agent.Job)
+	poll := func() {
+		polled <- agents.Poll("agent1", 1, nil, nil)
+	}
+	go poll()
+
+	host := acquireAgent(t, pool)
+	finished := make(chan error)
+	go func() {
+		_, err := host.runner.Run(nil, &script.Build{Image: "go1.2"}, &repo.Repo{Name: "github.com/drone/drone"}, nil, build.Limits{}, &bytes.Buffer{})
+		finished <- err
+	}()
+
+	// the response to the first poll is lost, so the
+	// build is not acknowledged and is offered again.
+	lost := <-polled
+	go poll()
+	job := <-polled
+	if job == nil || job.ID == lost.ID {
+		t.Fatalf("Expected the build to be offered again as a new job, got %v", job)
+	}
+	if err := agents.Ack(lost.ID); err != agent.ErrJobNotFound {
+		t.Errorf("Expected ErrJobNotFound for the expired job, got %v", err)
+	}
+
+	if err := agents.Ack(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * agentLease)
+	if err := agents.Done(job.ID, &agent.Result{State: &build.BuildState{ExitCode: 0}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-finished; err != nil {
+		t.Errorf("Expected build to pass, got %s", err)
+	}
+}
@@ -11,30 +11,45 @@ import (
 )
 
 type BuildRunner interface {
-	Run(buildScript *script.Build, repo *repo.Repo, key []byte, buildOutput io.Writer) (success bool, err error)
+	Run(dockerClient *docker.Client, buildScript *script.Build, repo *repo.Repo, key []byte, limits build.Limits, buildOutput io.Writer) (success bool, err error)
 }
 
 type buildRunner struct {
-	dockerClient *docker.Client
-	timeout      time.Duration
+	runtime string
+	timeout time.Duration
 }
 
-func NewBuildRunner(dockerClient *docker.Client, timeout time.Duration) BuildRunner {
-	return &buildRunner{
-		dockerClient: dockerClient,
-		timeout:      timeout,
+// NewBuildRunner creates a BuildRunner that runs each build
+// with the named runtime, such as docker or shell, using the
+// Docker client of the host the build is scheduled onto.
+func NewBuildRunner(runtime string, timeout time.Duration) (BuildRunner, error) {
+	// make sure the runtime exists before
+	// any builds are run.
+	if _, err := build.NewRuntime(runtime, nil); err != nil {
+		return nil, err
 	}
+
+	return &buildRunner{
+		runtime: runtime,
+		timeout: timeout,
+	}, nil
 }
 
-func (runner *buildRunner) Run(buildScript *script.Build, repo *repo.Repo, key []byte, buildOutput io.Writer) (bool, error) {
-	builder := build.New(runner.dockerClient)
+func (runner *buildRunner) Run(dockerClient *docker.Client, buildScript *script.Build, repo *repo.Repo, key []byte, limits build.Limits, buildOutput io.Writer) (bool, error) {
+	runtime, err := build.NewRuntime(runner.runtime, dockerClient)
+	if err != nil {
+		return true, err
+	}
+
+	builder := build.New(runtime)
 	builder.Build = buildScript
 	builder.Repo = repo
 	builder.Key = key
+	builder.Limits = limits
 	builder.Stdout = buildOutput
 	builder.Timeout = runner.timeout
 
-	err := builder.Run()
+	err = builder.Run()
 
 	return builder.BuildState == nil || builder.BuildState.ExitCode != 0, err
 }
@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"log"
+	"strings"
+
+	"github.com/drone/drone/pkg/database"
+	. "github.com/drone/drone/pkg/model"
+)
+
+// maxUpstreamDepth limits how far the chain of upstream
+// builds is followed when checking for cycles.
+const maxUpstreamDepth = 10
+
+// database lookups, and the creation of downstream build
+// tasks, which are replaced in tests.
+var (
+	getRepoSlug   = database.GetRepoSlug
+	getCommitHash = database.GetCommitHash
+	getBuildSlug  = database.GetBuildSlug
+	newTask       = NewTask
+)
+
+// triggerDownstream adds a build of the default branch head
+// for each downstream repository listed in the .drone.yml
+// file or the repository settings.
+func (w *worker) triggerDownstream(task *BuildTask) {
+	slugs := append(task.Repo.DownstreamList(), task.Script.Downstream...)
+	if len(slugs) == 0 {
+		return
+	}
+
+	upstream := task.Repo.Slug + "/commit/" + task.Commit.Hash
+
+	// repositories in the upstream chain are never
+	// rebuilt, to prevent a cycle of builds.
+	skip := upstreamRepos(task.Build)
+	skip[task.Repo.Slug] = true
+
+	for _, slug := range slugs {
+		if skip[slug] {
+			continue
+		}
+		skip[slug] = true
+
+		repo, err := getRepoSlug(slug)
+		if err != nil {
+			log.Printf("error getting downstream repo %s: %s\n", slug, err)
+			continue
+		}
+		if repo.Disabled {
+			continue
+		}
+
+		// a repository may only trigger builds of
+		// repositories with the same owner or team.
+		if repo.UserID != task.Repo.UserID && (repo.TeamID == 0 || repo.TeamID != task.Repo.TeamID) {
+			log.Printf("error triggering downstream repo %s: not owned by the owner of %s\n", slug, task.Repo.Slug)
+			continue
+		}
+
+		downstream, err := newTask(repo, &Request{Branch: repo.DefaultBranch(), Trigger: TriggerUpstream, Upstream: upstream})
+		if err != nil {
+			log.Printf("error triggering downstream repo %s: %s\n", slug, err)
+			continue
+		}
+
+		w.queue.Add(downstream)
+	}
+}
+
+// upstreamRepos returns the slugs of all repositories in the
+// chain of upstream builds that triggered the build.
+func upstreamRepos(build *Build) map[string]bool {
+	repos := map[string]bool{}
+	for i := 0; i < maxUpstreamDepth && len(build.Upstream) != 0; i++ {
+		parts := strings.SplitN(build.Upstream, "/commit/", 2)
+		if len(parts) != 2 {
+			break
+		}
+		repos[parts[0]] = true
+
+		repo, err := getRepoSlug(parts[0])
+		if err != nil {
+			break
+		}
+		commit, err := getCommitHash(parts[1], repo.ID)
+		if err != nil {
+			break
+		}
+		build, err = getBuildSlug("1", commit.ID)
+		if err != nil {
+			break
+		}
+	}
+	return repos
+}
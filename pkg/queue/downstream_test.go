@@ -0,0 +1,170 @@
+package queue
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/drone/drone/pkg/build/script"
+	"github.com/drone/drone/pkg/database"
+	. "github.com/drone/drone/pkg/model"
+)
+
+// stubDownstream replaces the database lookups with the
+// given repositories, commits and builds, for the duration
+// of a test. Commits are keyed by hash, and builds by the
+// commit ID.
+func stubDownstream(repos []*Repo, commits []*Commit, builds []*Build) func() {
+	getRepoSlug = func(slug string) (*Repo, error) {
+		for _, repo := range repos {
+			if repo.Slug == slug {
+				return repo, nil
+			}
+		}
+		return nil, sql.ErrNoRows
+	}
+	getCommitHash = func(hash string, repo int64) (*Commit, error) {
+		for _, commit := range commits {
+			if commit.Hash == hash && commit.RepoID == repo {
+				return commit, nil
+			}
+		}
+		return nil, sql.ErrNoRows
+	}
+	getBuildSlug = func(slug string, commit int64) (*Build, error) {
+		for _, build := range builds {
+			if build.Slug == slug && build.CommitID == commit {
+				return build, nil
+			}
+		}
+		return nil, sql.ErrNoRows
+	}
+	return func() {
+		getRepoSlug = database.GetRepoSlug
+		getCommitHash = database.GetCommitHash
+		getBuildSlug = database.GetBuildSlug
+	}
+}
+
+func TestUpstreamRepos(t *testing.T) {
+	reset := stubDownstream(
+		[]*Repo{
+			{ID: 1, Slug: "github.com/drone/go-github"},
+			{ID: 2, Slug: "github.com/drone/routes"},
+		},
+		[]*Commit{
+			{ID: 10, RepoID: 1, Hash: "a1b2c3"},
+			{ID: 20, RepoID: 2, Hash: "d4e5f6"},
+		},
+		[]*Build{
+			{CommitID: 10, Slug: "1"},
+			{CommitID: 20, Slug: "1", Upstream: "github.com/drone/go-github/commit/a1b2c3"},
+		},
+	)
+	defer reset()
+
+	// the chain of upstream builds is followed to the
+	// build that was not triggered by an upstream build.
+	build := &Build{Upstream: "github.com/drone/routes/commit/d4e5f6"}
+	got := upstreamRepos(build)
+	expected := map[string]bool{"github.com/drone/routes": true, "github.com/drone/go-github": true}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected upstream repos %v, got %v", expected, got)
+	}
+
+	// an upstream commit that no longer exists ends the
+	// chain, but the upstream repository is included.
+	build = &Build{Upstream: "github.com/drone/routes/commit/000000"}
+	got = upstreamRepos(build)
+	expected = map[string]bool{"github.com/drone/routes": true}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected upstream repos %v, got %v", expected, got)
+	}
+
+	// a build without an upstream has no upstream repos
+	if got = upstreamRepos(&Build{}); len(got) != 0 {
+		t.Errorf("Expected no upstream repos, got %v", got)
+	}
+}
+
+func TestTriggerDownstream(t *testing.T) {
+	reset := stubDownstream(
+		[]*Repo{
+			{ID: 1, UserID: 1, Slug: "github.com/drone/drone"},
+			{ID: 2, UserID: 1, Slug: "github.com/drone/go-github"},
+			{ID: 3, UserID: 2, TeamID: 5, Slug: "github.com/drone/routes"},
+			{ID: 4, UserID: 2, Slug: "github.com/octocat/hello-world"},
+			{ID: 5, UserID: 1, Slug: "github.com/drone/disabled", Disabled: true},
+			{ID: 6, UserID: 2, TeamID: 6, Slug: "github.com/drone/other-team"},
+		},
+		[]*Commit{
+			{ID: 20, RepoID: 2, Hash: "a1b2c3"},
+		},
+		[]*Build{
+			{CommitID: 20, Slug: "1"},
+		},
+	)
+	defer reset()
+
+	var triggered []string
+	newTask = func(repo *Repo, req *Request) (*BuildTask, error) {
+		if req.Trigger != TriggerUpstream || req.Branch != "master" {
+			t.Errorf("Expected an upstream build of master, got %s build of %s", req.Trigger, req.Branch)
+		}
+		if req.Upstream != "github.com/drone/drone/commit/4f4c45b1d8a0" {
+			t.Errorf("Expected the upstream commit path, got %s", req.Upstream)
+		}
+		triggered = append(triggered, repo.Slug)
+		return &BuildTask{Repo: repo, Build: &Build{}}, nil
+	}
+	defer func() { newTask = NewTask }()
+
+	q := &Queue{pool: NewPool(nil, 1, nil)}
+	w := &worker{queue: q}
+	task := &BuildTask{
+		Repo: &Repo{
+			ID:         1,
+			UserID:     1,
+			TeamID:     5,
+			Slug:       "github.com/drone/drone",
+			Downstream: "github.com/drone/go-github, github.com/drone/routes github.com/octocat/hello-world",
+		},
+		Commit: &Commit{Hash: "4f4c45b1d8a0"},
+		Build:  &Build{Upstream: "github.com/drone/go-github/commit/a1b2c3"},
+		Script: &script.Build{
+			Downstream: []string{
+				"github.com/drone/drone",
+				"github.com/drone/routes",
+				"github.com/drone/disabled",
+				"github.com/drone/other-team",
+				"github.com/drone/missing",
+			},
+		},
+	}
+	w.triggerDownstream(task)
+
+	// the upstream repository and the repository itself
+	// are skipped to prevent a cycle, repositories are only
+	// triggered once, and only if they share the owner or
+	// the team of the repository.
+	expected := []string{"github.com/drone/routes"}
+	if !reflect.DeepEqual(triggered, expected) {
+		t.Errorf("Expected downstream builds %v, got %v", expected, triggered)
+	}
+	if len(q.pending) != len(expected) {
+		t.Errorf("Expected %d pending builds, got %d", len(expected), len(q.pending))
+	}
+
+	// the same owner is enough, without a team
+	triggered = nil
+	q.pending = nil
+	task.Repo.TeamID = 0
+	task.Build.Upstream = ""
+	task.Repo.Downstream = ""
+	task.Script.Downstream = []string{"github.com/drone/routes", "github.com/drone/go-github"}
+	w.triggerDownstream(task)
+	expected = []string{"github.com/drone/go-github"}
+	if !reflect.DeepEqual(triggered, expected) {
+		t.Errorf("Expected downstream builds %v, got %v", expected, triggered)
+	}
+}
@@ -0,0 +1,379 @@
+package queue
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drone/drone/pkg/agent"
+	"github.com/drone/drone/pkg/build"
+	"github.com/drone/drone/pkg/build/docker"
+	"github.com/drone/drone/pkg/build/script"
+	"github.com/drone/drone/pkg/database"
+)
+
+// HostLocal is the address of the default host, which
+// runs builds on the same machine as the Drone server.
+const HostLocal = "local"
+
+// Host is a Docker host that builds are scheduled onto.
+type Host struct {
+	// Address of the Docker daemon, for example
+	// tcp://10.0.0.2:4243.
+	Address string
+
+	// Concurrency is the maximum number of builds
+	// that run on the host at the same time.
+	Concurrency int
+
+	// Labels advertised by the host, which are
+	// matched against the labels required by
+	// each build.
+	Labels []string
+
+	// Running is the number of builds currently
+	// running on the host.
+	Running int
+
+	// Healthy is false if the Docker daemon could not
+	// be reached, in which case no builds are scheduled
+	// onto the host until it recovers.
+	Healthy bool
+
+	// Error is the reason the host is unhealthy.
+	Error string
+
+	// Docker client connected to the host, or nil
+	// if builds are not run in Docker.
+	Client *docker.Client
+
+	// LastSeen is the last time a remote agent
+	// polled the server for a build.
+	LastSeen time.Time
+
+	// runner used to run builds on the host instead
+	// of the queue's runner, such as a remote agent.
+	runner BuildRunner
+}
+
+// IsAgent returns true if the host is a remote
+// agent that pulls builds from the server.
+func (h *Host) IsAgent() bool {
+	return h.runner != nil
+}
+
+// Load returns the fraction of the host's capacity
+// that is in use.
+func (h *Host) Load() float64 {
+	return float64(h.Running) / float64(h.Concurrency)
+}
+
+// A Pool schedules builds onto a set of Docker hosts. Each
+// build runs on the least loaded healthy host.
+type Pool struct {
+	sync.Mutex
+
+	// changed receives when capacity may have
+	// been added, so the queue can schedule its
+	// pending builds.
+	changed chan bool
+
+	// local host that is used when no
+	// hosts are registered.
+	local *Host
+	hosts []*Host
+
+	// remote agents that have
+	// polled for builds.
+	agents []*Host
+}
+
+// NewPool creates a Pool that runs builds on the local
+// host, using the given Docker client, until hosts are
+// registered. The client is nil if builds are not run in
+// Docker, in which case registered hosts are ignored.
+func NewPool(client *docker.Client, concurrency int, labels []string) *Pool {
+	pool := &Pool{changed: make(chan bool, 1)}
+	pool.local = &Host{
+		Address:     HostLocal,
+		Concurrency: concurrency,
+		Labels:      labels,
+		Healthy:     true,
+		Client:      client,
+	}
+	pool.hosts = []*Host{pool.local}
+	return pool
+}
+
+// Reload updates the hosts in the pool from the nodes
+// registered in the database. Hosts that are still
+// registered keep their running builds and health.
+func (p *Pool) Reload() error {
+	if p.local.Client == nil {
+		return nil
+	}
+
+	nodes, err := database.ListNodes()
+	if err != nil {
+		return err
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	existing := map[string]*Host{}
+	for _, host := range p.hosts {
+		existing[host.Address] = host
+	}
+
+	var hosts []*Host
+	for _, node := range nodes {
+		host, ok := existing[node.Address]
+		if !ok {
+			host = &Host{
+				Address: node.Address,
+				Healthy: true,
+				Client:  docker.NewHost(node.Address, node.CertPath),
+			}
+		}
+		host.Concurrency = node.Concurrency
+		host.Labels = node.Labels
+		hosts = append(hosts, host)
+	}
+	if len(hosts) == 0 {
+		hosts = append(hosts, p.local)
+	}
+	p.hosts = hosts
+
+	// schedule any builds waiting for a host,
+	// since capacity may have been added.
+	p.broadcast()
+	return nil
+}
+
+// acquire reserves a build on the least loaded healthy
+// host with the labels that has capacity, or returns the
+// reason the build has to wait. The caller must hold the
+// lock.
+func (p *Pool) acquire(labels []string) (*Host, string) {
+	var best *Host
+	var matched, healthy int
+	for _, host := range p.all() {
+		if !script.MatchLabels(host.Labels, labels) {
+			continue
+		}
+		matched++
+		if !host.Healthy {
+			continue
+		}
+		healthy++
+		if host.Running >= host.Concurrency {
+			continue
+		}
+		if best == nil || host.Load() < best.Load() {
+			best = host
+		}
+	}
+	if best == nil {
+		return nil, waitReason(labels, matched, healthy)
+	}
+	best.Running++
+	return best, ""
+}
+
+// broadcast signals the queue to schedule its pending
+// builds, since capacity may have been added. The caller
+// must hold the lock.
+func (p *Pool) broadcast() {
+	select {
+	case p.changed <- true:
+	default:
+	}
+}
+
+// waitReason returns the reason a build that requires the
+// labels is waiting, given the number of hosts that match
+// the labels and how many of them are healthy.
+func waitReason(labels []string, matched, healthy int) string {
+	var required string
+	if len(labels) != 0 {
+		required = " with labels " + strings.Join(labels, ", ")
+	}
+
+	switch {
+	case matched == 0:
+		return "No build host" + required + " is available"
+	case healthy == 0:
+		return "All build hosts" + required + " are unhealthy"
+	default:
+		return "Waiting for a build host" + required + " to finish a build"
+	}
+}
+
+// Release frees the build reserved on the host. If the
+// build failed with an error the host is checked, and is
+// marked unhealthy if the Docker daemon cannot be reached.
+func (p *Pool) Release(host *Host, err error) {
+	if err != nil {
+		p.check(host)
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	host.Running--
+	p.broadcast()
+}
+
+// Hosts returns a snapshot of the hosts in the pool.
+func (p *Pool) Hosts() []Host {
+	p.Lock()
+	defer p.Unlock()
+
+	var hosts []Host
+	for _, host := range p.all() {
+		hosts = append(hosts, *host)
+	}
+	return hosts
+}
+
+// DockerHosts returns a snapshot of the local host and the
+// registered hosts that run builds in Docker. The local host
+// is included even if it is not used, since it may still have
+// containers from earlier builds.
+func (p *Pool) DockerHosts() []Host {
+	p.Lock()
+	defer p.Unlock()
+
+	var hosts []Host
+	if p.local.Client != nil {
+		hosts = append(hosts, *p.local)
+	}
+	for _, host := range p.hosts {
+		if host != p.local && host.Client != nil {
+			hosts = append(hosts, *host)
+		}
+	}
+	return hosts
+}
+
+// Reap removes the containers and images left behind by builds
+// on the healthy Docker hosts, and returns the number removed.
+// Remote agents remove their own orphaned containers.
+func (p *Pool) Reap() int {
+	var removed int
+	for _, host := range p.DockerHosts() {
+		if !host.Healthy {
+			continue
+		}
+		n, err := build.Reap(host.Client)
+		if err != nil {
+			log.Printf("error removing orphaned containers from %s: %s\n", host.Address, err)
+		}
+		removed += n
+	}
+	return removed
+}
+
+// StartReaper removes the containers and images left behind
+// if the server exits during a build, once at startup and
+// then at the given interval.
+func (p *Pool) StartReaper(interval time.Duration) {
+	go func() {
+		for {
+			if n := p.Reap(); n != 0 {
+				log.Printf("removed %d orphaned containers and images\n", n)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// all returns the registered hosts and remote agents.
+// The caller must hold the lock.
+func (p *Pool) all() []*Host {
+	hosts := make([]*Host, 0, len(p.hosts)+len(p.agents))
+	hosts = append(hosts, p.hosts...)
+	return append(hosts, p.agents...)
+}
+
+// seen adds the remote agent to the pool the first time
+// it polls for a build, and marks it healthy.
+func (p *Pool) seen(host *Host, concurrency int, labels []string) {
+	p.Lock()
+	defer p.Unlock()
+
+	if host.LastSeen.IsZero() {
+		p.agents = append(p.agents, host)
+	}
+	if !host.Healthy {
+		log.Printf("build agent %s is healthy\n", host.Address)
+	}
+	host.LastSeen = time.Now()
+	host.Concurrency = concurrency
+	host.Labels = labels
+	host.Healthy = true
+	host.Error = ""
+	p.broadcast()
+}
+
+// StartHealthCheck checks the health of every host at
+// the given interval, so that unhealthy hosts are used
+// again once they recover.
+func (p *Pool) StartHealthCheck(interval time.Duration) {
+	go func() {
+		for {
+			p.Lock()
+			hosts := p.all()
+			p.Unlock()
+
+			for _, host := range hosts {
+				p.check(host)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// check pings the Docker daemon and updates
+// the health of the host.
+func (p *Pool) check(host *Host) {
+	if host.IsAgent() {
+		p.checkAgent(host)
+		return
+	}
+	if host.Client == nil {
+		return
+	}
+	_, err := host.Client.Version()
+
+	p.Lock()
+	defer p.Unlock()
+
+	switch {
+	case err != nil && host.Healthy:
+		log.Printf("docker host %s is unhealthy: %s\n", host.Address, err)
+		host.Healthy = false
+		host.Error = err.Error()
+	case err == nil && !host.Healthy:
+		log.Printf("docker host %s is healthy\n", host.Address)
+		host.Healthy = true
+		host.Error = ""
+		p.broadcast()
+	}
+}
+
+// checkAgent marks the remote agent unhealthy if it has
+// a free build slot, but has not polled for a build.
+func (p *Pool) checkAgent(host *Host) {
+	p.Lock()
+	defer p.Unlock()
+
+	idle := time.Since(host.LastSeen)
+	if host.Healthy && host.Running < host.Concurrency && idle > 2*agent.PollTimeout {
+		log.Printf("build agent %s is unhealthy: last seen %s ago\n", host.Address, idle)
+		host.Healthy = false
+		host.Error = fmt.Sprintf("Agent has not polled for a build since %s", host.LastSeen.UTC().Format(time.RFC1123))
+	}
+}
@@ -0,0 +1,292 @@
+package queue
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/pkg/build"
+	"github.com/drone/drone/pkg/build/docker"
+	"github.com/drone/drone/pkg/build/repo"
+	"github.com/drone/drone/pkg/build/script"
+	"github.com/drone/drone/pkg/database"
+	. "github.com/drone/drone/pkg/model"
+)
+
+// stubPending records the reason each build is pending,
+// instead of saving the build, for the duration of a test.
+func stubPending() (chan string, func()) {
+	reasons := make(chan string, 10)
+	saveBuild = func(build *Build) error {
+		reasons <- build.Waiting
+		return nil
+	}
+	return reasons, func() { saveBuild = database.SaveBuild }
+}
+
+// drain discards the signal that capacity may have
+// changed, if any, so that the next signal is seen.
+func drain(pool *Pool) {
+	select {
+	case <-pool.changed:
+	default:
+	}
+}
+
+func TestPoolAcquire(t *testing.T) {
+	pool := NewPool(nil, 1, nil)
+	pool.hosts = []*Host{
+		{Address: "tcp://10.0.0.2:4243", Concurrency: 2, Healthy: true},
+		{Address: "tcp://10.0.0.3:4243", Concurrency: 4, Healthy: true},
+		{Address: "tcp://10.0.0.4:4243", Concurrency: 8, Healthy: false},
+	}
+
+	// builds are scheduled onto the least loaded
+	// healthy host, and never the unhealthy host.
+	var expected = []string{
+		"tcp://10.0.0.2:4243",
+		"tcp://10.0.0.3:4243",
+		"tcp://10.0.0.3:4243",
+		"tcp://10.0.0.2:4243",
+		"tcp://10.0.0.3:4243",
+		"tcp://10.0.0.3:4243",
+	}
+	q, started := testQueue(pool)
+	for i := range expected {
+		q.Add(testTask(strconv.Itoa(i + 1)))
+	}
+	q.dispatch()
+
+	for i, address := range expected {
+		select {
+		case host := <-started:
+			if host.Address != address {
+				t.Errorf("Expected build %d on %s, got %s", i, address, host.Address)
+			}
+		default:
+			t.Fatalf("Expected build %d to start", i)
+		}
+	}
+}
+
+func TestPoolRelease(t *testing.T) {
+	reasons, reset := stubPending()
+	defer reset()
+
+	pool := NewPool(nil, 1, nil)
+	q, started := testQueue(pool)
+	q.Add(testTask("1"))
+	q.Add(testTask("2"))
+	q.dispatch()
+	host := <-started
+
+	// the pool is full, so the second build must
+	// wait until the first build is released.
+	select {
+	case <-started:
+		t.Fatalf("Expected build to wait for a host")
+	default:
+	}
+	if reason := <-reasons; reason != "Waiting for a build host to finish a build" {
+		t.Errorf("Expected build to wait for the host, got %q", reason)
+	}
+
+	// releasing the host signals the queue
+	drain(pool)
+	pool.Release(host, nil)
+	select {
+	case <-pool.changed:
+	default:
+		t.Fatalf("Expected the queue to be signalled when the host was released")
+	}
+	q.dispatch()
+
+	select {
+	case h := <-started:
+		if h != host {
+			t.Errorf("Expected build on %s, got %s", host.Address, h.Address)
+		}
+	default:
+		t.Errorf("Expected build to run after the host was released")
+	}
+}
+
+func TestPoolAcquireLabels(t *testing.T) {
+	pool := NewPool(nil, 1, nil)
+	pool.hosts = []*Host{
+		{Address: "tcp://10.0.0.2:4243", Concurrency: 1, Healthy: true},
+		{Address: "tcp://10.0.0.3:4243", Concurrency: 1, Healthy: true, Labels: []string{"privileged"}},
+	}
+
+	q, started := testQueue(pool)
+	q.Add(testTask("1", "privileged"))
+	q.Add(testTask("2", "!privileged"))
+	q.dispatch()
+
+	if host := <-started; host.Address != "tcp://10.0.0.3:4243" {
+		t.Errorf("Expected privileged build on tcp://10.0.0.3:4243, got %s", host.Address)
+	}
+	if host := <-started; host.Address != "tcp://10.0.0.2:4243" {
+		t.Errorf("Expected unprivileged build on tcp://10.0.0.2:4243, got %s", host.Address)
+	}
+}
+
+func TestPoolAcquireWaiting(t *testing.T) {
+	reasons, reset := stubPending()
+	defer reset()
+
+	// no host has the label, so the build waits
+	// and explains why.
+	pool := NewPool(nil, 1, nil)
+	q, started := testQueue(pool)
+	q.Add(testTask("1", "highmem"))
+	q.dispatch()
+	if reason := <-reasons; reason != "No build host with labels highmem is available" {
+		t.Errorf("Expected build to wait for a highmem host, got %q", reason)
+	}
+
+	// builds without labels are not held up by
+	// the waiting build.
+	q.Add(testTask("2"))
+	q.dispatch()
+	select {
+	case host := <-started:
+		if host.Address != HostLocal {
+			t.Errorf("Expected build on the local host, got %s", host.Address)
+		}
+	default:
+		t.Fatalf("Expected build to run on the local host")
+	}
+
+	// the build runs once an agent with the
+	// label polls for builds.
+	agent := &Host{Address: "agent://agent1", runner: &agentRunner{}}
+	drain(pool)
+	pool.seen(agent, 1, []string{"highmem"})
+	select {
+	case <-pool.changed:
+	default:
+		t.Fatalf("Expected the queue to be signalled when the agent polled")
+	}
+	q.dispatch()
+
+	select {
+	case host := <-started:
+		if host != agent {
+			t.Errorf("Expected build on agent://agent1, got %s", host.Address)
+		}
+	default:
+		t.Errorf("Expected build to run on the highmem agent")
+	}
+}
+
+// remoteDaemon is a fake Docker daemon on a remote host,
+// which records the files copied into the build container
+// and the host config used to start it.
+type remoteDaemon struct {
+	sync.Mutex
+	files map[string]string
+	host  *docker.HostConfig
+}
+
+func (d *remoteDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.Lock()
+	defer d.Unlock()
+
+	path := r.URL.Path
+	if i := strings.Index(path[1:], "/"); strings.HasPrefix(path, "/v") && i != -1 {
+		path = path[i+1:]
+	}
+	switch {
+	case path == "/version":
+		fmt.Fprint(w, `{"Version":"1.8.0","ApiVersion":"1.20"}`)
+	case path == "/containers/create":
+		fmt.Fprint(w, `{"Id":"abc"}`)
+	case path == "/containers/abc/archive":
+		d.files = map[string]string{}
+		archive := tar.NewReader(r.Body)
+		for {
+			header, err := archive.Next()
+			if err != nil {
+				break
+			}
+			data, _ := ioutil.ReadAll(archive)
+			d.files[header.Name] = string(data)
+		}
+	case path == "/containers/abc/start":
+		d.host = &docker.HostConfig{}
+		json.NewDecoder(r.Body).Decode(d.host)
+		w.WriteHeader(http.StatusNoContent)
+	case path == "/containers/abc/attach":
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Type: application/vnd.docker.raw-stream\r\n\r\n"))
+		conn.Close()
+	case path == "/containers/abc/wait":
+		fmt.Fprint(w, `{"StatusCode":0}`)
+	case r.Method == "GET" && strings.HasPrefix(path, "/images/"):
+		fmt.Fprint(w, `{}`)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestPoolRemoteHost(t *testing.T) {
+	daemon := &remoteDaemon{}
+	server := httptest.NewServer(daemon)
+	defer server.Close()
+
+	src, err := ioutil.TempDir("", "TestPoolRemoteHost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	ioutil.WriteFile(filepath.Join(src, "main.go"), []byte("package main"), 0644)
+
+	// the local Docker daemon can not be reached, so the
+	// build must run on the registered host, which does
+	// not share the filesystem of the server.
+	pool := NewPool(docker.NewHost("tcp://127.0.0.1:1", ""), 1, nil)
+	pool.hosts = []*Host{
+		{Address: "tcp://" + strings.TrimPrefix(server.URL, "http://"), Concurrency: 1, Healthy: true},
+	}
+	pool.hosts[0].Client = docker.NewHost(pool.hosts[0].Address, "")
+
+	q, started := testQueue(pool)
+	q.Add(testTask("1"))
+	q.dispatch()
+	host := <-started
+	defer pool.Release(host, nil)
+
+	runner, err := NewBuildRunner(build.RuntimeDocker, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buildScript := &script.Build{Image: "go1.2", Script: []string{"go test"}}
+	buildRepo := &repo.Repo{Path: src, Dir: "/var/cache/drone/src/local/app"}
+	failed, err := runner.Run(host.Client, buildScript, buildRepo, []byte("private key"), build.Limits{}, ioutil.Discard)
+	if err != nil || failed {
+		t.Fatalf("Expected build to succeed on the remote host, got %v", err)
+	}
+
+	daemon.Lock()
+	defer daemon.Unlock()
+	if daemon.host == nil || len(daemon.host.Binds) != 0 {
+		t.Errorf("Expected build container without bind mounts, got %v", daemon.host)
+	}
+	if daemon.files["drone/id_rsa"] != "private key" {
+		t.Errorf("Expected the identity file to be copied to the remote host, got %v", daemon.files)
+	}
+	if daemon.files["var/cache/drone/src/local/app/main.go"] != "package main" {
+		t.Errorf("Expected the source to be copied to the remote host, got %v", daemon.files)
+	}
+}
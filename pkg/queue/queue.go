@@ -1,13 +1,34 @@
 package queue
 
 import (
+	"log"
+	"sync"
+
 	"github.com/drone/drone/pkg/build/script"
+	"github.com/drone/drone/pkg/database"
 	. "github.com/drone/drone/pkg/model"
 )
 
+// saveBuild persists the reason a build is pending,
+// and is replaced in tests.
+var saveBuild = database.SaveBuild
+
 // A Queue dispatches tasks to workers.
 type Queue struct {
-	tasks chan<- *BuildTask
+	// pool of Docker hosts that
+	// builds are scheduled onto.
+	pool *Pool
+
+	runner BuildRunner
+
+	// start runs the task on the host reserved
+	// for it, in the background.
+	start func(task *BuildTask, host *Host)
+
+	// pending tasks that are waiting for
+	// a host, oldest first.
+	sync.Mutex
+	pending []*BuildTask
 }
 
 // BuildTasks represents a build that is pending
@@ -22,24 +43,86 @@ type BuildTask struct {
 	Script *script.Build
 }
 
-// Start N workers with the given build runner.
-func Start(workers int, runner BuildRunner) *Queue {
-	tasks := make(chan *BuildTask)
+// Start dispatches tasks to workers with the given build
+// runner. Each build runs on a host from the pool, and
+// waits until a host has capacity.
+func Start(pool *Pool, runner BuildRunner) *Queue {
+	q := &Queue{
+		pool:   pool,
+		runner: runner,
+	}
+	q.start = func(task *BuildTask, host *Host) {
+		go q.run(task, host)
+	}
+	go q.listen()
+	return q
+}
 
-	queue := &Queue{tasks: tasks}
+// listen dispatches the pending tasks each time the
+// capacity of the pool may have changed.
+func (q *Queue) listen() {
+	for _ = range q.pool.changed {
+		q.dispatch()
+	}
+}
 
-	for i := 0; i < workers; i++ {
-		worker := worker{
-			runner: runner,
-		}
+// Add adds the task to the build queue. The build waits
+// until a host with the labels required by the build has
+// capacity, and is then executed.
+//
+// Each time capacity is freed, it goes to the oldest task
+// that is able to use it, so a build that requires labels
+// no host has does not hold up the builds behind it.
+func (q *Queue) Add(task *BuildTask) {
+	q.Lock()
+	q.pending = append(q.pending, task)
+	q.Unlock()
+
+	q.pool.Lock()
+	q.pool.broadcast()
+	q.pool.Unlock()
+}
 
-		go worker.work(tasks)
+// dispatch starts each pending task, oldest first, for
+// which a host has capacity, and records why the other
+// tasks are still pending.
+func (q *Queue) dispatch() {
+	var waiting []*BuildTask
+
+	q.Lock()
+	q.pool.Lock()
+	pending := q.pending[:0]
+	for _, task := range q.pending {
+		host, reason := q.pool.acquire(task.Script.Labels)
+		if host != nil {
+			q.start(task, host)
+			continue
+		}
+		pending = append(pending, task)
+		if task.Build.Waiting != reason {
+			task.Build.Waiting = reason
+			waiting = append(waiting, task)
+		}
 	}
+	q.pending = pending
+	q.pool.Unlock()
+	q.Unlock()
 
-	return queue
+	// explain why the builds are still pending
+	for _, task := range waiting {
+		if err := saveBuild(task.Build); err != nil {
+			log.Printf("error saving pending build: %s\n", err)
+		}
+	}
 }
 
-// Add adds the task to the build queue.
-func (q *Queue) Add(task *BuildTask) {
-	q.tasks <- task
+// run executes the task on the host reserved for it.
+func (q *Queue) run(task *BuildTask, host *Host) {
+	worker := worker{
+		runner: q.runner,
+		queue:  q,
+		host:   host,
+	}
+	worker.execute(task)
+	q.pool.Release(host, worker.err)
 }
@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/drone/drone/pkg/build/script"
+	"github.com/drone/drone/pkg/database"
+	. "github.com/drone/drone/pkg/model"
+)
+
+// testTask returns a task for the build that
+// requires the labels.
+func testTask(slug string, labels ...string) *BuildTask {
+	return &BuildTask{Build: &Build{Slug: slug}, Script: &script.Build{Labels: labels}}
+}
+
+// testQueue returns a queue on the pool that sends the
+// host reserved for each task to the channel, instead
+// of running the task.
+func testQueue(pool *Pool) (*Queue, chan *Host) {
+	started := make(chan *Host, 10)
+	q := &Queue{pool: pool}
+	q.start = func(task *BuildTask, host *Host) {
+		started <- host
+	}
+	return q, started
+}
+
+func TestQueueDispatch(t *testing.T) {
+	saveBuild = func(build *Build) error { return nil }
+	defer func() { saveBuild = database.SaveBuild }()
+
+	pool := NewPool(nil, 1, nil)
+	pool.hosts = []*Host{
+		{Address: "tcp://10.0.0.2:4243", Concurrency: 1, Healthy: true},
+		{Address: "tcp://10.0.0.3:4243", Concurrency: 1, Healthy: true, Labels: []string{"highmem"}},
+	}
+
+	var started []string
+	q := &Queue{pool: pool}
+	q.start = func(task *BuildTask, host *Host) {
+		started = append(started, task.Build.Slug+" "+host.Address)
+	}
+
+	q.pending = []*BuildTask{
+		testTask("1"),
+		testTask("2"),
+		testTask("3", "gpu"),
+		testTask("4", "highmem"),
+		testTask("5"),
+	}
+	q.dispatch()
+
+	// builds are started oldest first, and a build that
+	// no host is able to run does not hold up the rest.
+	var expected = []string{
+		"1 tcp://10.0.0.2:4243",
+		"2 tcp://10.0.0.3:4243",
+	}
+	if len(started) != len(expected) || started[0] != expected[0] || started[1] != expected[1] {
+		t.Fatalf("Expected builds %v to start, got %v", expected, started)
+	}
+	if len(q.pending) != 3 || q.pending[0].Build.Slug != "3" || q.pending[1].Build.Slug != "4" {
+		t.Fatalf("Expected builds 3, 4 and 5 to be pending, got %d", len(q.pending))
+	}
+	if reason := q.pending[0].Build.Waiting; reason != "No build host with labels gpu is available" {
+		t.Errorf("Expected build 3 to wait for a gpu host, got %q", reason)
+	}
+
+	// the freed host goes to the oldest build that is
+	// able to use it, even though build 5 could use it.
+	started = nil
+	pool.Release(pool.hosts[1], nil)
+	q.dispatch()
+	if len(started) != 1 || started[0] != "4 tcp://10.0.0.3:4243" {
+		t.Errorf("Expected build 4 to start on the highmem host, got %v", started)
+	}
+
+	started = nil
+	pool.Release(pool.hosts[0], nil)
+	q.dispatch()
+	if len(started) != 1 || started[0] != "5 tcp://10.0.0.2:4243" {
+		t.Errorf("Expected build 5 to start, got %v", started)
+	}
+}
@@ -0,0 +1,60 @@
+package queue
+
+import (
+	"log"
+	"time"
+
+	"github.com/drone/drone/pkg/database"
+)
+
+// StartScheduler starts a background routine that checks
+// the repository schedules once a minute and adds a build
+// task to the queue for each schedule that is due.
+func (q *Queue) StartScheduler() {
+	go func() {
+		for now := range time.Tick(time.Minute) {
+			q.schedule(now)
+		}
+	}()
+}
+
+// schedule enqueues a build for every schedule
+// that is due at time t.
+func (q *Queue) schedule(t time.Time) {
+	schedules, err := database.ListAllSchedules()
+	if err != nil {
+		log.Printf("error listing schedules: %s\n", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		if !schedule.IsDue(t) {
+			continue
+		}
+
+		// record the run before building so that a failure
+		// is not retried every minute until the next match.
+		schedule.LastRun = t.UTC().Truncate(time.Minute)
+		if err := database.SaveSchedule(schedule); err != nil {
+			log.Printf("error saving schedule %d: %s\n", schedule.ID, err)
+			continue
+		}
+
+		repo, err := database.GetRepo(schedule.RepoID)
+		if err != nil {
+			log.Printf("error getting repo for schedule %d: %s\n", schedule.ID, err)
+			continue
+		}
+		if repo.Disabled {
+			continue
+		}
+
+		task, err := NewBranchTask(repo, schedule.Branch, TriggerCron)
+		if err != nil {
+			log.Printf("error scheduling build for %s %s: %s\n", repo.Slug, schedule.Branch, err)
+			continue
+		}
+
+		q.Add(task)
+	}
+}
@@ -0,0 +1,262 @@
+package queue
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/drone/drone/pkg/build/script"
+	"github.com/drone/drone/pkg/database"
+	. "github.com/drone/drone/pkg/model"
+	"github.com/drone/go-github/github"
+)
+
+// Trigger describes the event that caused a build
+// and is exposed to the build as DRONE_TRIGGER.
+const (
+	TriggerPush        = "push"
+	TriggerPullRequest = "pull_request"
+	TriggerCron        = "cron"
+	TriggerManual      = "manual"
+	TriggerUpstream    = "upstream"
+)
+
+var (
+	ErrBuildInProgress = errors.New("A build for this commit is already pending or running")
+)
+
+// Request describes a build that is triggered
+// outside of a GitHub post-commit hook.
+type Request struct {
+	// Branch is the branch to build.
+	Branch string
+
+	// Commit is an optional sha to build. If
+	// empty the head of the Branch is built.
+	Commit string
+
+	// Params are merged with the repository Params,
+	// replacing existing values with the same key.
+	Params map[string]string
+
+	// Trigger is the event that caused the build.
+	Trigger string
+
+	// User is the user that triggered the build,
+	// if any.
+	User *User
+
+	// Upstream is the path of the upstream commit
+	// that triggered the build, if any.
+	Upstream string
+}
+
+// NewBranchTask creates a build task for the head commit
+// of the named branch.
+func NewBranchTask(repo *Repo, branch, trigger string) (*BuildTask, error) {
+	return NewTask(repo, &Request{Branch: branch, Trigger: trigger})
+}
+
+// NewTask creates a build task for the requested branch
+// and commit. The commit and build are persisted with a
+// Pending status, but the task is not added to the queue.
+//
+// If the commit was already built for this branch, the
+// existing commit is reset and rebuilt.
+func NewTask(repo *Repo, req *Request) (*BuildTask, error) {
+	// get the user that owns the repository
+	// since we need his / her GitHub token
+	user, err := database.GetUser(repo.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := database.SettingsMust()
+
+	ref := req.Commit
+	if len(ref) == 0 {
+		ref = req.Branch
+	}
+	head, err := findCommit(settings.GitHubApiUrl, user.GithubToken, repo.Owner, repo.Name, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// merge the request params with the repository
+	// params. A copy of the repository is used so the
+	// overrides only apply to this build.
+	if len(req.Params) != 0 {
+		params := map[string]string{}
+		for k, v := range repo.Params {
+			params[k] = v
+		}
+		for k, v := range req.Params {
+			params[k] = v
+		}
+		override := *repo
+		override.Params = params
+		repo = &override
+	}
+
+	// get the drone.yml file from GitHub
+	buildscript, err := findScript(settings.GitHubApiUrl, user.GithubToken, repo, head.Sha)
+	if err != nil {
+		return nil, err
+	}
+
+	// re-use the commit if this hash was already built
+	// for the branch, since commits are unique per repo,
+	// hash and branch.
+	commit, err := database.GetCommitBranchHash(req.Branch, head.Sha, repo.ID)
+	switch {
+	case err != nil && err != sql.ErrNoRows:
+		return nil, err
+	case err == nil:
+		if commit.Status == StatusEnqueue || commit.Status == StatusStarted {
+			return nil, ErrBuildInProgress
+		}
+	default:
+		commit = &Commit{}
+		commit.RepoID = repo.ID
+		commit.Branch = req.Branch
+		commit.Hash = head.Sha
+		commit.Message = head.Commit.Message
+		commit.Timestamp = head.Commit.Author.Date
+		commit.SetAuthor(head.Commit.Author.Email)
+	}
+	commit.Status = StatusEnqueue
+	commit.Duration = 0
+	if err := database.SaveCommit(commit); err != nil {
+		return nil, err
+	}
+
+	build, err := database.GetBuildSlug("1", commit.ID)
+	if err != nil {
+		build = &Build{}
+		build.Slug = "1" // TODO
+		build.CommitID = commit.ID
+	}
+	build.Created = time.Now().UTC()
+	build.Status = StatusEnqueue
+	build.Duration = 0
+	build.Stdout = ""
+	build.Trigger = req.Trigger
+	build.TriggeredBy = ""
+	build.Upstream = req.Upstream
+	if req.User != nil {
+		build.TriggeredBy = req.User.Name
+	}
+	if err := database.SaveBuild(build); err != nil {
+		return nil, err
+	}
+
+	return &BuildTask{Repo: repo, Commit: commit, Build: build, Script: buildscript}, nil
+}
+
+// headCommit is the subset of the GitHub commit
+// resource used to create a build.
+type headCommit struct {
+	Sha    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+		Author  struct {
+			Email string `json:"email"`
+			Date  string `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+// githubTimeout is the maximum amount of time spent on
+// a request to the GitHub API, so that a slow response
+// cannot stall the scheduler indefinitely.
+var githubTimeout = 30 * time.Second
+
+// githubClient is the HTTP client used for requests to
+// the GitHub API. Each request uses a new connection, with
+// a deadline that covers connecting, sending the request
+// and reading the response.
+var githubClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		Dial:              githubDial,
+		DisableKeepAlives: true,
+	},
+}
+
+// githubDial connects to the address, and sets the
+// deadline of the connection to githubTimeout from now.
+func githubDial(network, addr string) (net.Conn, error) {
+	conn, err := net.DialTimeout(network, addr, githubTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(githubTimeout))
+	return conn, nil
+}
+
+// findCommit uses the GitHub API to resolve the branch,
+// tag or sha to a single commit.
+func findCommit(api, token, owner, name, ref string) (*headCommit, error) {
+	// the ref is escaped, but may include slashes,
+	// such as the name of a feature/ branch.
+	escaped := strings.TrimPrefix((&url.URL{Path: ref}).String(), "./")
+	path := fmt.Sprintf("%s/repos/%s/%s/commits/%s", api, owner, name, escaped)
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+token)
+
+	resp, err := githubClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to find %s in %s/%s, GitHub returned %s", ref, owner, name, resp.Status)
+	}
+
+	head := headCommit{}
+	if err := json.NewDecoder(resp.Body).Decode(&head); err != nil {
+		return nil, err
+	}
+	return &head, nil
+}
+
+// FindScript fetches and parses the .drone.yml file of
+// the repository at the given commit.
+func FindScript(repo *Repo, sha string) (*script.Build, error) {
+	user, err := database.GetUser(repo.UserID)
+	if err != nil {
+		return nil, err
+	}
+	settings := database.SettingsMust()
+	return findScript(settings.GitHubApiUrl, user.GithubToken, repo, sha)
+}
+
+// findScript fetches the .drone.yml file from GitHub,
+// and validates and parses it with the repository Params.
+func findScript(api, token string, repo *Repo, sha string) (*script.Build, error) {
+	client := github.New(token)
+	client.ApiUrl = api
+
+	content, err := client.Contents.FindRef(repo.Owner, repo.Name, ".drone.yml", sha)
+	if err != nil {
+		return nil, fmt.Errorf("No .drone.yml was found in %s at %s", repo.Slug, sha)
+	}
+	raw, err := content.DecodeContent()
+	if err != nil {
+		return nil, err
+	}
+	if problems := script.Validate(raw, repo.Params); problems.HasErrors() {
+		return nil, fmt.Errorf("Your .drone.yml file is invalid.\n\n%s", problems)
+	}
+	return script.ParseBuild(raw, repo.Params)
+}
@@ -0,0 +1,62 @@
+package queue
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFindCommit(t *testing.T) {
+	var uri, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uri, auth = r.RequestURI, r.Header.Get("Authorization")
+		w.Write([]byte(`{"sha":"4f4c45b1d8a0","commit":{"message":"fixed #42","author":{"email":"brad@drone.io"}}}`))
+	}))
+	defer server.Close()
+
+	head, err := findCommit(server.URL, "3a2b1c", "drone", "drone", "feature/a b#1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Sha != "4f4c45b1d8a0" || head.Commit.Message != "fixed #42" {
+		t.Errorf("Expected the head commit, got %+v", head)
+	}
+
+	// the ref is escaped, except for slashes
+	if uri != "/repos/drone/drone/commits/feature/a%20b%231" {
+		t.Errorf("Expected the escaped ref, got %s", uri)
+	}
+	if auth != "token 3a2b1c" {
+		t.Errorf("Expected the token, got %s", auth)
+	}
+}
+
+func TestFindCommitTimeout(t *testing.T) {
+	timeout := githubTimeout
+	githubTimeout = 50 * time.Millisecond
+	defer func() { githubTimeout = timeout }()
+
+	release := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// a GitHub request that does not respond must
+	// not stall the scheduler.
+	done := make(chan error, 1)
+	go func() {
+		_, err := findCommit(server.URL, "3a2b1c", "drone", "drone", "master")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Expected a timeout error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the request to time out")
+	}
+}
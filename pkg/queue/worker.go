@@ -3,6 +3,7 @@ package queue
 import (
 	"bytes"
 	"fmt"
+	"github.com/drone/drone/pkg/build"
 	"github.com/drone/drone/pkg/build/git"
 	r "github.com/drone/drone/pkg/build/repo"
 	"github.com/drone/drone/pkg/channel"
@@ -13,28 +14,24 @@ import (
 	"io"
 	"log"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
 type worker struct {
 	runner BuildRunner
-}
 
-// work is a function that will infinitely
-// run in the background waiting for tasks that
-// it can pull off the queue and execute.
-func (w *worker) work(queue <-chan *BuildTask) {
-	var task *BuildTask
-	for {
-		// get work item (pointer) from the queue
-		task = <-queue
-		if task == nil {
-			continue
-		}
+	// queue is used to add builds of
+	// downstream repositories.
+	queue *Queue
 
-		// execute the task
-		w.execute(task)
-	}
+	// host that the build runs on.
+	host *Host
+
+	// err is the error returned by the build
+	// runner, if any, which is used to check
+	// the health of the host.
+	err error
 }
 
 // execute will execute the build task and persist
@@ -56,11 +53,21 @@ func (w *worker) execute(task *BuildTask) error {
 		}
 	}()
 
+	// the status of the previous build of the branch, or of
+	// the pull request, is used to notify when the branch is
+	// fixed or broken.
+	var prevStatus string
+	if prev, err := database.GetBranchPrev(task.Repo.ID, task.Commit.Branch, task.Commit.PullRequest, task.Commit.ID); err == nil {
+		prevStatus = prev.Status
+	}
+
 	// update commit and build status
 	task.Commit.Status = "Started"
 	task.Build.Status = "Started"
 	task.Build.Started = time.Now().UTC()
 	task.Commit.Started = time.Now().UTC()
+	task.Build.Node = w.host.Address
+	task.Build.Waiting = ""
 
 	// persist the commit to the database
 	if err := database.SaveCommit(task.Commit); err != nil {
@@ -77,15 +84,18 @@ func (w *worker) execute(task *BuildTask) error {
 
 	// notification context
 	context := &notify.Context{
-		Repo:   task.Repo,
-		Commit: task.Commit,
-		Host:   settings.URL().String(),
+		Repo:             task.Repo,
+		Commit:           task.Commit,
+		Build:            task.Build,
+		Host:             settings.URL().String(),
+		PrevStatus:       prevStatus,
+		SaveNotification: database.SaveNotification,
+		SaveDelivery:     database.SaveDelivery,
+		FindUser:         database.GetUserGithubLogin,
 	}
 
 	// send all "started" notifications
-	if task.Script.Notifications != nil {
-		task.Script.Notifications.Send(context)
-	}
+	sendNotifications(task, context)
 
 	// Send "started" notification to Github
 	if err := updateGitHubStatus(task.Repo, task.Commit); err != nil {
@@ -116,6 +126,11 @@ func (w *worker) execute(task *BuildTask) error {
 		}
 	}
 
+	// expose the event that triggered the build
+	if len(task.Build.Trigger) != 0 {
+		task.Script.Env = append(task.Script.Env, "DRONE_TRIGGER="+task.Build.Trigger)
+	}
+
 	defer func() {
 		// update the status of the commit using the
 		// GitHub status API.
@@ -125,7 +140,8 @@ func (w *worker) execute(task *BuildTask) error {
 	}()
 
 	// execute the build
-	passed, buildErr := w.runBuild(task, buf)
+	passed, buildErr := w.runBuild(task, settings, buf)
+	w.err = buildErr
 
 	task.Build.Finished = time.Now().UTC()
 	task.Commit.Finished = time.Now().UTC()
@@ -161,14 +177,32 @@ func (w *worker) execute(task *BuildTask) error {
 	channel.Close(consoleslug)
 
 	// send all "finished" notifications
-	if task.Script.Notifications != nil {
-		task.Script.Notifications.Send(context)
+	sendNotifications(task, context)
+
+	// build downstream repositories, iff the build
+	// passed and this is not a pull request
+	if task.Commit.Status == StatusSuccess && len(task.Commit.PullRequest) == 0 {
+		w.triggerDownstream(task)
 	}
 
 	return nil
 }
 
-func (w *worker) runBuild(task *BuildTask, buf io.Writer) (bool, error) {
+// sendNotifications sends the notifications configured
+// in the build script, and logs the errors of any failed
+// notifier against the build.
+func sendNotifications(task *BuildTask, context *notify.Context) {
+	if task.Script.Notifications == nil {
+		return
+	}
+	if err := task.Script.Notifications.Send(context); err != nil {
+		log.Printf("error sending %s notifications for %s/%s/%s commit %s build %s: %s\n",
+			strings.ToLower(task.Commit.Status), task.Repo.Host, task.Repo.Owner, task.Repo.Name,
+			task.Commit.HashShort(), task.Build.Slug, err)
+	}
+}
+
+func (w *worker) runBuild(task *BuildTask, settings *Settings, buf io.Writer) (bool, error) {
 	repo := &r.Repo{
 		Name:   task.Repo.Slug,
 		Path:   task.Repo.URL,
@@ -179,14 +213,58 @@ func (w *worker) runBuild(task *BuildTask, buf io.Writer) (bool, error) {
 		Depth:  git.GitDepth(task.Script.Git),
 	}
 
-	return w.runner.Run(
+	// builds on a remote agent are run by
+	// the agent instead of the server.
+	runner := w.runner
+	if w.host.runner != nil {
+		runner = w.host.runner
+	}
+
+	return runner.Run(
+		w.host.Client,
 		task.Script,
 		repo,
 		[]byte(task.Repo.PrivateKey),
+		buildLimits(settings, task.Repo),
 		buf,
 	)
 }
 
+// megabyte is the number of bytes in a megabyte, used
+// to convert the resource limits stored in megabytes.
+const megabyte = 1 << 20
+
+// buildLimits returns the resource limits of the build and
+// service containers. Limits set for the repository override
+// the system defaults.
+func buildLimits(settings *Settings, repo *Repo) build.Limits {
+	memory, swap, cpuShares := settings.BuildMemory, settings.BuildSwap, settings.BuildCpuShares
+	if repo.Memory != 0 {
+		memory = repo.Memory
+	}
+	if repo.Swap != 0 {
+		swap = repo.Swap
+	}
+	if repo.CpuShares != 0 {
+		cpuShares = repo.CpuShares
+	}
+
+	limits := build.Limits{CpuShares: cpuShares}
+
+	// Docker limits the total of memory and swap, and
+	// ignores the swap limit if memory is not limited.
+	if memory > 0 {
+		limits.Memory = memory * megabyte
+		switch {
+		case swap < 0:
+			limits.MemorySwap = -1
+		case swap > 0:
+			limits.MemorySwap = (memory + swap) * megabyte
+		}
+	}
+	return limits
+}
+
 // updateGitHubStatus is a helper function that will send
 // the build status to GitHub using the Status API.
 // see https://github.com/blog/1227-commit-status-api
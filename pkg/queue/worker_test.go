@@ -0,0 +1,34 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/drone/drone/pkg/build"
+	. "github.com/drone/drone/pkg/model"
+)
+
+func TestBuildLimits(t *testing.T) {
+	var tests = []struct {
+		settings Settings
+		repo     Repo
+		limits   build.Limits
+	}{
+		// no limits
+		{Settings{}, Repo{}, build.Limits{}},
+		// system defaults
+		{Settings{BuildMemory: 512, BuildSwap: 256, BuildCpuShares: 512}, Repo{}, build.Limits{Memory: 512 << 20, MemorySwap: 768 << 20, CpuShares: 512}},
+		// swap disabled
+		{Settings{BuildMemory: 512, BuildSwap: -1}, Repo{}, build.Limits{Memory: 512 << 20, MemorySwap: -1}},
+		// swap is ignored without a memory limit
+		{Settings{BuildSwap: 256}, Repo{}, build.Limits{}},
+		// repository overrides
+		{Settings{BuildMemory: 512, BuildSwap: -1, BuildCpuShares: 512}, Repo{Memory: 2048, CpuShares: 1024}, build.Limits{Memory: 2048 << 20, MemorySwap: -1, CpuShares: 1024}},
+		{Settings{BuildMemory: 512, BuildSwap: -1}, Repo{Swap: 512}, build.Limits{Memory: 512 << 20, MemorySwap: 1024 << 20}},
+	}
+
+	for _, test := range tests {
+		if limits := buildLimits(&test.settings, &test.repo); limits != test.limits {
+			t.Errorf("Expected limits %+v, got %+v", test.limits, limits)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"html/template"
 	"io"
 	"strings"
+	texttemplate "text/template"
 
 	"github.com/GeertJohan/go.rice"
 )
@@ -19,9 +20,18 @@ var ErrTemplateNotFound = errors.New("Template Not Found")
 // is the template name and the value is the *template.Template.
 var registry = map[string]*template.Template{}
 
+// textRegistry stores a map of plain-text Templates, such as
+// the plain-text alternative of an email, which must not be
+// HTML escaped.
+var textRegistry = map[string]*texttemplate.Template{}
+
 // ExecuteTemplate applies the template associated with t that has
 // the given name to the specified data object and writes the output to wr.
 func ExecuteTemplate(wr io.Writer, name string, data interface{}) error {
+	if templ, ok := textRegistry[name]; ok {
+		return templ.Execute(wr, data)
+	}
+
 	templ, ok := registry[name]
 	if !ok {
 		return ErrTemplateNotFound
@@ -73,6 +83,8 @@ func init() {
 		"repo_settings.html",
 		"repo_delete.html",
 		"repo_params.html",
+		"repo_schedules.html",
+		"repo_deliveries.html",
 		"repo_badges.html",
 		"repo_keys.html",
 		"repo_commit.html",
@@ -80,6 +92,8 @@ func init() {
 		"admin_users_edit.html",
 		"admin_users_add.html",
 		"admin_settings.html",
+		"admin_nodes.html",
+		"admin_docker.html",
 		"github_add.html",
 		"github_link.html",
 	}
@@ -171,4 +185,22 @@ func init() {
 		// parse the template and then add to the global map
 		registry[file] = emailParsed
 	}
+
+	// plain-text alternatives of the emails
+	files = []string{
+		"failure.txt",
+		"success.txt",
+	}
+
+	for _, file := range files {
+		text, err := box.String(file)
+		if err != nil {
+			panic(err)
+		}
+		textParsed, err := texttemplate.New(file).Parse(text)
+		if err != nil {
+			panic(fmt.Errorf("Error parsing email template for %s: %s", file, err))
+		}
+		textRegistry[file] = textParsed
+	}
 }